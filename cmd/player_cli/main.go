@@ -13,7 +13,7 @@ import (
 )
 
 func init() {
-	stop := make(chan os.Signal)
+	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
@@ -28,6 +28,7 @@ func init() {
 func main() {
 	cb := &callbackMain{}
 
+	core.SetAppVersion("cli")
 	core.InitApp(
 		false,
 		"85dea59886138936d3b1a573f6069357",
@@ -35,6 +36,7 @@ func main() {
 		"/Users/petr/dev/apps/pult/player_core/tmp/data",
 		"/Users/petr/dev/apps/pult/player_core/tmp/cache",
 		cb,
+		&playerOutput{},
 	)
 
 	core.Run()
@@ -1,8 +1,64 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/qkveri/player_core/core"
+)
 
 func openPlayerScreen() {
-	// player
-	fmt.Println("OPEN MAIN SCREEN")
+	fmt.Println("\n▶️  Плеер. Команды: [p] пауза, [r] играть, [n] следующий, [s] стоп, [l] выход")
+
+	go func() {
+		for {
+			switch waitInput() {
+			case "p":
+				core.Pause()
+			case "r":
+				core.Play()
+			case "n":
+				core.Next()
+			case "s":
+				core.Stop()
+			case "l":
+				// дальше ввод читает экран входа
+				core.Logout(false)
+				return
+			}
+		}
+	}()
+}
+
+// playerOutput - заглушка нативного плеера, только печатает команды.
+type playerOutput struct {
+}
+
+func (o *playerOutput) Play(id int, filePath string, offsetMs int64) error {
+	fmt.Printf("🎵 Play #%d: %s (offset %dms)\n", id, filePath, offsetMs)
+	return nil
+}
+
+func (o *playerOutput) Ramp(id int, fromVolume, toVolume float64, durationMs int64) error {
+	fmt.Printf("🔉 Ramp #%d: %.1f → %.1f (%dms)\n", id, fromVolume, toVolume, durationMs)
+	return nil
+}
+
+func (o *playerOutput) SetVolume(volume float64, durationMs int64) error {
+	fmt.Printf("🔊 Volume: %.1f (%dms)\n", volume, durationMs)
+	return nil
+}
+
+func (o *playerOutput) Pause() error {
+	fmt.Println("⏸  Pause")
+	return nil
+}
+
+func (o *playerOutput) Resume() error {
+	fmt.Println("⏯  Resume")
+	return nil
+}
+
+func (o *playerOutput) Stop(id int) error {
+	fmt.Printf("⏹  Stop #%d\n", id)
+	return nil
 }
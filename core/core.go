@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"strings"
 
 	"github.com/qkveri/player_core/pkg/app"
 )
@@ -10,6 +11,9 @@ type (
 	CallbackMain     interface{ app.CallbackMain }
 	CallbackLoadData interface{ app.CallbackLoadData }
 	CallbackLogin    interface{ app.CallbackLogin }
+	CallbackCache    interface{ app.CallbackCache }
+	CallbackVenue    interface{ app.CallbackVenue }
+	PlayerOutput     interface{ app.PlayerOutput }
 )
 
 var (
@@ -17,11 +21,19 @@ var (
 
 	a *app.App
 
+	// версия приложения хоста, задается до InitApp
+	appVersion string
+
 	// callbacks
 	cbLoadData CallbackLoadData
 	cbLogin    CallbackLogin
 )
 
+// SetAppVersion - версия приложения хоста для heartbeat, вызывать до InitApp.
+func SetAppVersion(version string) {
+	appVersion = version
+}
+
 func InitApp(
 	debug bool,
 	secretKey string,
@@ -30,9 +42,11 @@ func InitApp(
 	cacheDir string,
 
 	callbackMain CallbackMain,
+	playerOutput PlayerOutput,
 ) {
 	config := app.Config{
-		Debug: debug,
+		Debug:      debug,
+		AppVersion: appVersion,
 
 		SecretKey:  secretKey,
 		ApiBaseURL: apiBaseURL,
@@ -41,7 +55,7 @@ func InitApp(
 		CacheDir: cacheDir,
 	}
 
-	a = app.NewApp(config, callbackMain)
+	a = app.NewApp(config, callbackMain, playerOutput)
 	a.Init()
 }
 
@@ -68,3 +82,54 @@ func RegisterLoginCallback(callback CallbackLogin) {
 func Login(code string) {
 	a.Login(ctx, cbLogin, code)
 }
+
+// Logout отвязывает устройство от заведения и показывает экран входа, clearCache - удалить и скачанные треки.
+func Logout(clearCache bool) {
+	a.Logout(ctx, clearCache)
+}
+
+func RegisterCacheCallback(callback CallbackCache) {
+	a.RegisterCacheCallback(callback)
+}
+
+func RegisterVenueCallback(callback CallbackVenue) {
+	a.RegisterVenueCallback(callback)
+}
+
+func Play() {
+	a.Play()
+}
+
+func Pause() {
+	a.Pause()
+}
+
+func Next() {
+	a.Next()
+}
+
+func Stop() {
+	a.Stop()
+}
+
+// SetDownloadRateLimit - лимит скорости скачивания в байтах в секунду, 0 - без лимита.
+func SetDownloadRateLimit(bytesPerSecond int64) {
+	a.SetDownloadRateLimit(bytesPerSecond)
+}
+
+func SetMeteredNetwork(metered bool) {
+	a.SetMeteredNetwork(metered)
+}
+
+// SetCdnMirrors - запасные CDN через запятую, по порядку.
+func SetCdnMirrors(mirrors string) {
+	var list []string
+
+	for _, m := range strings.Split(mirrors, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			list = append(list, m)
+		}
+	}
+
+	a.SetCdnMirrors(list)
+}
@@ -8,12 +8,15 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 )
 
 type httpClient struct {
 	client  *http.Client
 	baseURL string
 
+	// токен меняется на ходу, пока идут запросы
+	hm          sync.RWMutex
 	baseHeaders http.Header
 }
 
@@ -31,7 +34,16 @@ func NewHTTPClient(baseURL string) *httpClient {
 	}
 }
 
+// SetAuthToken - пустой токен убирает авторизацию.
 func (c *httpClient) SetAuthToken(token string) {
+	c.hm.Lock()
+	defer c.hm.Unlock()
+
+	if token == "" {
+		c.baseHeaders.Del("Authorization")
+		return
+	}
+
 	c.baseHeaders.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 }
 
@@ -77,7 +89,9 @@ func (c *httpClient) do(ctx context.Context, method, path string, body io.Reader
 	}
 
 	// set base headers
+	c.hm.RLock()
 	req.Header = c.baseHeaders.Clone()
+	c.hm.RUnlock()
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"errors"
+)
+
+// unauthorizedClient сообщает об ответе 401 на любой запрос, ошибка возвращается как есть.
+type unauthorizedClient struct {
+	Client
+
+	onUnauthorized func(err *UnauthorizedError)
+}
+
+func WithUnauthorizedHandler(client Client, onUnauthorized func(err *UnauthorizedError)) *unauthorizedClient {
+	return &unauthorizedClient{
+		Client:         client,
+		onUnauthorized: onUnauthorized,
+	}
+}
+
+func (c *unauthorizedClient) GET(ctx context.Context, path string) ([]byte, error) {
+	res, err := c.Client.GET(ctx, path)
+
+	return res, c.check(err)
+}
+
+func (c *unauthorizedClient) POST(ctx context.Context, path string, body interface{}) ([]byte, error) {
+	res, err := c.Client.POST(ctx, path, body)
+
+	return res, c.check(err)
+}
+
+func (c *unauthorizedClient) check(err error) error {
+	var unauthorizedErr *UnauthorizedError
+
+	if errors.As(err, &unauthorizedErr) {
+		c.onUnauthorized(unauthorizedErr)
+	}
+
+	return err
+}
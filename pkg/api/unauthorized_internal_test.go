@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_unauthorizedClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case return204Path:
+			w.WriteHeader(http.StatusNoContent)
+
+		case "/music-data":
+			if r.Header.Get("Authorization") != "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":{"message":"token revoked"}}`))
+
+				return
+			}
+
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	var calls int
+
+	client := WithUnauthorizedHandler(NewHTTPClient(srv.URL), func(err *UnauthorizedError) {
+		calls++
+
+		if err.Message != "token revoked" {
+			t.Errorf("got message %q", err.Message)
+		}
+	})
+
+	client.SetAuthToken("token")
+
+	if _, err := client.GET(context.Background(), "/music-data"); err == nil {
+		t.Fatal("got nil error, want unauthorized")
+	}
+
+	// без токена заголовка нет, другие ошибки не считаются
+	client.SetAuthToken("")
+
+	if _, err := client.POST(context.Background(), "/music-data", nil); err == nil {
+		t.Fatal("got nil error, want forbidden")
+	}
+
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
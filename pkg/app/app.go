@@ -8,44 +8,112 @@ import (
 	"log"
 	"os"
 	"path"
+	"sync"
 	"time"
+	// база часовых поясов: на iOS и части Android-приставок системной нет
+	_ "time/tzdata"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/oklog/run"
 	"github.com/rs/zerolog"
 
 	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/cache"
 	"github.com/qkveri/player_core/pkg/domain"
 	"github.com/qkveri/player_core/pkg/domain/repositories"
 	"github.com/qkveri/player_core/pkg/services/downloader"
+	"github.com/qkveri/player_core/pkg/services/heartbeat"
+	"github.com/qkveri/player_core/pkg/services/player"
 	"github.com/qkveri/player_core/pkg/services/playlister"
+	"github.com/qkveri/player_core/pkg/services/playlog"
+	"github.com/qkveri/player_core/pkg/services/remote"
+	"github.com/qkveri/player_core/pkg/services/syncer"
+	"github.com/qkveri/player_core/pkg/services/timesync"
 	"github.com/qkveri/player_core/pkg/state"
 	"github.com/qkveri/player_core/pkg/utils"
 )
 
+type timeSyncService interface {
+	Clock() clockwork.Clock
+	Offset() time.Duration
+	Run(ctx context.Context) error
+}
+
+type syncerService interface {
+	Reload(ctx context.Context) error
+	Run(ctx context.Context) error
+}
+
+type remoteService interface {
+	Run(ctx context.Context) error
+}
+
+type playLogService interface {
+	player.Observer
+	Load(ctx context.Context)
+	Flush(ctx context.Context) error
+	Clear(ctx context.Context) error
+	Run(ctx context.Context) error
+	RunUploader(ctx context.Context) error
+}
+
 type App struct {
 	config       Config
 	callbackMain CallbackMain
+	playerOutput PlayerOutput
+
+	cbm           sync.RWMutex
+	callbackCache CallbackCache
+	callbackVenue CallbackVenue
+	// данные загружены, показан экран плеера или закрытого заведения
+	dataLoaded bool
+
+	startedAt time.Time
+	// файл лога этого запуска, пустой - лог не пишется в файл
+	logFilePath string
+	// последняя ошибка для службы поддержки
+	em          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
 
 	state     *state.State
 	logger    zerolog.Logger
 	apiClient api.Client
+	auth      *authState
 
 	// repos...
-	playerInfoRepo domain.PlayerInfoRepository
-	loginRepo      domain.LoginRepository
-	musicDataRepo  domain.MusicDataRepository
-	authRepo       domain.AuthRepository
+	playerInfoRepo      domain.PlayerInfoRepository
+	playerInfoLocalRepo domain.PlayerInfoLocalRepository
+	loginRepo           domain.LoginRepository
+	musicDataRepo       domain.MusicDataRepository
+	musicDataLocalRepo  domain.MusicDataLocalRepository
+	authRepo            domain.AuthRepository
+	logSender           domain.LogSender
+
+	cacheIndex domain.CacheIndex
+	cacheTouch *cacheTouchObserver
+	history    *historyRecorder
+
+	// services...
+	timeSync   timeSyncService
+	player     playerService
+	downloader downloaderService
+	playLog    playLogService
+	syncer     syncerService
+	remote     remoteService
 }
 
-func NewApp(config Config, callbackMain CallbackMain) *App {
+func NewApp(config Config, callbackMain CallbackMain, playerOutput PlayerOutput) *App {
 	return &App{
-		config:       config,
+		config:       config.withDefaults(),
 		callbackMain: callbackMain,
+		playerOutput: playerOutput,
 	}
 }
 
 func (a *App) Init() {
+	a.startedAt = time.Now()
+
 	// init state...
 	a.state = state.NewState()
 
@@ -53,35 +121,147 @@ func (a *App) Init() {
 	a.logger = a.iniLogger()
 
 	// init common...
-	a.apiClient = api.NewHTTPClient(a.config.ApiBaseURL)
+	a.auth = newAuthState()
+	a.apiClient = api.WithUnauthorizedHandler(api.NewHTTPClient(a.config.ApiBaseURL), a.onUnauthorized)
 
 	// init repos...
-	a.playerInfoRepo = repositories.NewPlayerInfoApiRepo(a.apiClient)
+	a.playerInfoLocalRepo = repositories.NewPlayerInfoFileRepo(path.Join(a.config.DataDir, "pi.dat"), a.config.SecretKey)
+	a.playerInfoRepo = repositories.NewPlayerInfoFallbackRepo(
+		repositories.NewPlayerInfoApiRepo(a.apiClient),
+		a.playerInfoLocalRepo,
+		a.logger,
+	)
 	a.loginRepo = repositories.NewLoginApiRepo(a.apiClient)
-	a.musicDataRepo = repositories.NewMusicDataApiRepo(a.apiClient)
+	a.musicDataLocalRepo = repositories.NewMusicDataFileRepo(path.Join(a.config.DataDir, "md.dat"), a.config.SecretKey)
+	a.musicDataRepo = repositories.NewMusicDataFallbackRepo(
+		repositories.NewMusicDataApiRepo(a.apiClient),
+		a.musicDataLocalRepo,
+		a.logger,
+	)
 	a.authRepo = repositories.NewAuthFileRepo(path.Join(a.config.DataDir, "a.tk"), a.config.SecretKey)
+	a.logSender = repositories.NewLogApiRepo(a.apiClient)
+
+	a.cacheIndex = cache.NewFileIndex(a.mp3RootDir(), a.logger)
+	a.cacheTouch = newCacheTouchObserver(a.cacheIndex, a.logger)
+	a.history = newHistoryRecorder(a.state,
+		repositories.NewHistoryFileRepo(path.Join(a.config.DataDir, "h.dat"), a.config.SecretKey), a.logger)
+	a.history.Load(context.Background())
+	a.playLog = playlog.NewService(
+		repositories.NewPlayLogFileRepo(path.Join(a.config.DataDir, "pl.dat"), a.config.SecretKey),
+		repositories.NewPlayLogApiRepo(a.apiClient),
+		a.logger,
+	)
+	a.playLog.Load(context.Background())
+
+	// init services...
+	a.timeSync = timesync.NewService(clockwork.NewRealClock(), a.logger, a.sendErrorMessage,
+		repositories.NewPlayerInfoApiRepo(a.apiClient), a.config.TimeSyncInterval, a.config.ClockDriftThreshold)
+	a.player = player.NewService(a.state, a.logger, clockwork.NewRealClock(), a.newPlayerOutput(), a.sendErrorMessage,
+		a.config.CrossFadeExcludeAds)
+	a.player.AddObserver(a.cacheTouch)
+	a.player.AddObserver(&serverTimeObserver{observer: a.history, offset: a.timeSync.Offset})
+	a.player.AddObserver(&serverTimeObserver{observer: a.playLog, offset: a.timeSync.Offset})
+	a.downloader = downloader.NewService(a.state, a.logger, a.timeSync.Clock(), a.sendErrorMessage, a.sendCacheCoverage, a.cacheIndex,
+		a.mp3RootDir(), downloader.Config{
+			Prefetch:      a.config.PrefetchLibrary,
+			Concurrency:   a.config.DownloadConcurrency,
+			CacheMaxSize:  a.config.CacheMaxSize,
+			RateLimit:     a.config.DownloadRateLimit,
+			Metered:       a.config.MeteredNetwork,
+			MeteredTracks: a.config.MeteredTracks,
+			CdnMirrors:    a.config.CdnMirrors,
+		})
+	a.syncer = syncer.NewService(a.state, a.logger, a.playerInfoRepo, a.musicDataRepo, a.config.SyncInterval)
+	a.remote = remote.NewService(repositories.NewCommandApiRepo(a.apiClient), &remoteController{app: a}, a.logger)
 }
 
 func (a *App) Run(ctx context.Context) {
 	// show first screen...
 	a.showScreen(ScreenLoadingData)
 
-	errCb := func(err error) {
-		a.callbackMain.SendErrorMessage(a.errMessageForClient(err))
-	}
+	errCb := a.sendErrorMessage
 
 	g := run.Group{}
 
+	// run timesync service...
+	g.Add(func() error {
+		return a.runWhileAuthorized(ctx, a.timeSync.Run)
+	}, func(err error) {
+		//
+	})
+
 	// run playlister service...
 	g.Add(func() error {
-		return playlister.NewService(a.state, a.logger, clockwork.NewRealClock()).Run(ctx)
+		return playlister.NewService(a.state, a.logger, a.timeSync.Clock(), playlister.Rules{
+			AdMaxDelay:       a.config.AdMaxDelay,
+			ArtistSeparation: a.config.ArtistSeparation,
+			TrackRepeat:      a.config.TrackRepeatInterval,
+			ShuffleBag:       a.config.ShuffleBag,
+		}, a.config.Timezone, a.onVenueChanged).Run(ctx)
+	}, func(err error) {
+		//
+	})
+
+	// run player service...
+	g.Add(func() error {
+		return a.player.Run(ctx)
+	}, func(err error) {
+		//
+	})
+
+	// run cache touch observer...
+	g.Add(func() error {
+		return a.cacheTouch.Run(ctx)
+	}, func(err error) {
+		//
+	})
+
+	// run history recorder...
+	g.Add(func() error {
+		return a.history.Run(ctx)
+	}, func(err error) {
+		//
+	})
+
+	// run play log service...
+	g.Add(func() error {
+		return a.playLog.Run(ctx)
+	}, func(err error) {
+		//
+	})
+
+	// run play log uploader...
+	g.Add(func() error {
+		return a.runWhileAuthorized(ctx, a.playLog.RunUploader)
+	}, func(err error) {
+		//
+	})
+
+	// run heartbeat service...
+	g.Add(func() error {
+		return a.runWhileAuthorized(ctx, heartbeat.NewService(repositories.NewHeartbeatApiRepo(a.apiClient),
+			a.deviceStatus, a.logger, a.config.HeartbeatInterval).Run)
+	}, func(err error) {
+		//
+	})
+
+	// run syncer service...
+	g.Add(func() error {
+		return a.runWhileAuthorized(ctx, a.syncer.Run)
+	}, func(err error) {
+		//
+	})
+
+	// run remote commands service...
+	g.Add(func() error {
+		return a.runWhileAuthorized(ctx, a.remote.Run)
 	}, func(err error) {
 		//
 	})
 
 	// run downloader service...
 	g.Add(func() error {
-		mp3RootDir := path.Join(a.config.CacheDir, "m")
+		mp3RootDir := a.mp3RootDir()
 
 		if err := utils.MkDirIfNotExists(mp3RootDir); err != nil {
 			err = fmt.Errorf("cannot MkDirIfNotExists: %w, mp3RootDir: %s", err, mp3RootDir)
@@ -91,7 +271,7 @@ func (a *App) Run(ctx context.Context) {
 			return err
 		}
 
-		return downloader.NewService(a.state, a.logger, errCb, mp3RootDir).Run(ctx)
+		return a.downloader.Run(ctx)
 	}, func(err error) {
 		//
 	})
@@ -105,6 +285,10 @@ func (a *App) Run(ctx context.Context) {
 	}
 }
 
+func (a *App) mp3RootDir() string {
+	return path.Join(a.config.CacheDir, "m")
+}
+
 func (a *App) iniLogger() zerolog.Logger {
 	var output io.Writer
 
@@ -146,6 +330,8 @@ func (a *App) createLogFile() (*os.File, error) {
 		return nil, fmt.Errorf("os.Create: %w, filePath: %s", err, filePath)
 	}
 
+	a.logFilePath = filePath
+
 	return file, nil
 }
 
@@ -160,6 +346,32 @@ func (a *App) showScreen(name string) {
 	a.callbackMain.ShowScreen(name)
 }
 
+func (a *App) RegisterCacheCallback(callback CallbackCache) {
+	a.cbm.Lock()
+	a.callbackCache = callback
+	a.cbm.Unlock()
+}
+
+func (a *App) sendCacheCoverage(coverage domain.CacheCoverage) {
+	a.cbm.RLock()
+	defer a.cbm.RUnlock()
+
+	if a.callbackCache == nil {
+		return
+	}
+
+	a.callbackCache.SendCacheCoverage(coverage.CachedTracks, coverage.TotalTracks, coverage.CachedBytes,
+		coverage.UsageBytes, coverage.LimitBytes)
+}
+
+func (a *App) sendErrorMessage(err error) {
+	a.em.Lock()
+	a.lastError, a.lastErrorAt = err.Error(), time.Now()
+	a.em.Unlock()
+
+	a.callbackMain.SendErrorMessage(a.errMessageForClient(err))
+}
+
 func (a *App) errMessageForClient(err error) string {
 	if a.config.Debug {
 		return err.Error()
@@ -171,5 +383,8 @@ func (a *App) errMessageForClient(err error) string {
 
 	case *api.NoInternetError:
 		return "Нет подключения к интернету"
+
+	case *timesync.DriftError:
+		return "Время на устройстве отличается от точного, проверьте настройки даты и времени"
 	}
 }
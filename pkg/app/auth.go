@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/qkveri/player_core/pkg/api"
+)
+
+// authState - есть ли действующая авторизация. changed закрывается при каждом изменении.
+type authState struct {
+	mu         sync.Mutex
+	authorized bool
+	changed    chan struct{}
+}
+
+func newAuthState() *authState {
+	return &authState{changed: make(chan struct{})}
+}
+
+// set возвращает false, если состояние не изменилось.
+func (s *authState) set(authorized bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authorized == authorized {
+		return false
+	}
+
+	s.authorized = authorized
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return true
+}
+
+func (s *authState) get() (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.authorized, s.changed
+}
+
+// runWhileAuthorized запускает сервис, работающий с API, только пока есть авторизация:
+// при ее потере сервис останавливается и ждет нового входа.
+func (a *App) runWhileAuthorized(ctx context.Context, run func(ctx context.Context) error) error {
+	for {
+		authorized, changed := a.auth.get()
+
+		if !authorized {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+
+			continue
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+
+		go func() {
+			select {
+			case <-changed:
+			case <-runCtx.Done():
+			}
+
+			cancel()
+		}()
+
+		err := run(runCtx)
+
+		cancel()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+}
+
+func (a *App) setAuthorized(token string) {
+	a.apiClient.SetAuthToken(token)
+	a.auth.set(true)
+}
+
+// onUnauthorized - API ответило 401 на любой запрос: токен отозван, нужен новый вход.
+// Сервисы API останавливаются, плеер доигрывает кеш, если это разрешено.
+func (a *App) onUnauthorized(err *api.UnauthorizedError) {
+	if authorized, _ := a.auth.get(); !authorized {
+		return
+	}
+
+	a.logger.Warn().Err(err).Msg("authorization revoked")
+
+	if !a.config.PlayWhileUnauthorized {
+		a.player.Stop()
+	}
+
+	dropped, dropErr := a.dropAuth(context.Background())
+
+	if dropErr != nil {
+		a.logger.Err(dropErr).Msg("drop auth fail")
+	}
+
+	// одновременные 401 показывают экран один раз
+	if dropped {
+		a.showScreen(ScreenLogin)
+	}
+}
+
+// dropAuth забывает авторизацию, сервисы API останавливаются. Экран входа показывает вызывающий.
+// Возвращает false, если авторизации уже не было.
+func (a *App) dropAuth(ctx context.Context) (bool, error) {
+	if !a.auth.set(false) {
+		return false, nil
+	}
+
+	a.apiClient.SetAuthToken("")
+
+	a.cbm.Lock()
+	a.dataLoaded = false
+	a.cbm.Unlock()
+
+	if err := a.authRepo.Delete(ctx); err != nil {
+		return true, fmt.Errorf("cannot delete auth: %w", err)
+	}
+
+	return true, nil
+}
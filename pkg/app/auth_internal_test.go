@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const testSecretKey = "85dea59886138936d3b1a573f6069357"
+
+type fakeCallbackMain struct {
+	mu      sync.Mutex
+	screens []string
+}
+
+func (f *fakeCallbackMain) ShowScreen(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.screens = append(f.screens, name)
+}
+
+func (f *fakeCallbackMain) SendErrorMessage(string) {}
+
+func (f *fakeCallbackMain) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+
+	for _, s := range f.screens {
+		if s == name {
+			n++
+		}
+	}
+
+	return n
+}
+
+// newTestApp - приложение с данными во временном каталоге и API на apiURL.
+func newTestApp(t *testing.T, apiURL string) (*App, *fakeCallbackMain, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "app")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := &fakeCallbackMain{}
+	a := NewApp(Config{SecretKey: testSecretKey, ApiBaseURL: apiURL, DataDir: dir, CacheDir: dir}, callback, nil)
+	a.Init()
+
+	return a, callback, func() { _ = os.RemoveAll(dir) }
+}
+
+// apiHandler - API, отвечающее 401 на запросы с отозванным токеном.
+func apiHandler(handle func(w http.ResponseWriter, r *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/return_204" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if strings.Contains(r.Header.Get("Authorization"), "revoked") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"token revoked"}}`))
+
+			return
+		}
+
+		if handle != nil {
+			handle(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":null}`))
+	})
+}
+
+func receive(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %s", what)
+	}
+}
+
+func Test_runWhileAuthorized(t *testing.T) {
+	srv := httptest.NewServer(apiHandler(nil))
+	defer srv.Close()
+
+	a, callback, cleanup := newTestApp(t, srv.URL)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started, stopped := make(chan struct{}, 1), make(chan struct{}, 1)
+
+	go func() {
+		_ = a.runWhileAuthorized(ctx, func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+
+			return ctx.Err()
+		})
+	}()
+
+	if err := a.authRepo.Set(ctx, &domain.Auth{PlayerID: 1, Token: "revoked"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.setAuthorized("revoked")
+	receive(t, started, "start")
+
+	// любой запрос с отозванным токеном останавливает сервисы API, экран входа - один раз
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := a.apiClient.GET(ctx, "/player/info"); err == nil {
+				t.Error("got nil error, want unauthorized")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	receive(t, stopped, "stop")
+
+	if got := callback.count(ScreenLogin); got != 1 {
+		t.Errorf("got login screen shown %d times, want 1", got)
+	}
+
+	if auth, err := a.authRepo.Get(ctx); err != nil || auth != nil {
+		t.Errorf("got auth %v, %v, want deleted", auth, err)
+	}
+
+	// повторный 401 уже ничего не меняет
+	_, _ = a.apiClient.GET(ctx, "/player/info")
+
+	if got := callback.count(ScreenLogin); got != 1 {
+		t.Errorf("got login screen shown %d times after second 401, want 1", got)
+	}
+
+	a.setAuthorized("valid")
+	receive(t, started, "restart")
+}
@@ -1,19 +1,124 @@
 package app
 
+import "time"
+
+const (
+	defaultAdMaxDelay          = 15 * time.Minute
+	defaultSyncInterval        = 5 * time.Minute
+	defaultTimeSyncInterval    = 30 * time.Minute
+	defaultClockDriftThreshold = 2 * time.Minute
+	defaultHeartbeatInterval   = 5 * time.Minute
+	defaultDownloadConcurrency = 2
+	defaultCacheMaxSize        = 2 << 30
+	defaultMeteredTracks       = 2
+	defaultArtistSeparation    = 3
+	defaultTrackRepeatInterval = 3 * time.Hour
+)
+
 const (
 	ScreenLoadingData = "loading"
 	ScreenLogin       = "login"
 	ScreenPlayer      = "player"
+	// заведение закрыто, музыка не играет
+	ScreenClosed = "closed"
 )
 
 type Config struct {
 	Debug bool
+	// версия приложения хоста, уходит на сервер с состоянием плеера
+	AppVersion string
 
 	SecretKey  string
 	ApiBaseURL string
 
 	DataDir  string
 	CacheDir string
+
+	// реклама, опоздавшая больше чем на AdMaxDelay, не играет, отрицательный - без лимита
+	AdMaxDelay time.Duration
+
+	// IANA-пояс заведения, если его не прислал сервер, пустой - пояс устройства
+	Timezone string
+
+	// после отзыва авторизации доигрывать скачанное, а не останавливать плеер
+	PlayWhileUnauthorized bool
+
+	// не делать кроссфейд с рекламой
+	CrossFadeExcludeAds bool
+
+	// минимум треков между треками одного исполнителя, отрицательный - без правила
+	ArtistSeparation int
+	// минимум времени до повтора трека, отрицательный - без правила
+	TrackRepeatInterval time.Duration
+	// каждый трек интервала играет по разу, прежде чем какой-то повторится
+	ShuffleBag bool
+
+	// как часто проверять обновление музыкальных настроек
+	SyncInterval time.Duration
+	// как часто сверять часы с сервером
+	TimeSyncInterval time.Duration
+	// при таком расхождении часов устройства с сервером показывается предупреждение
+	ClockDriftThreshold time.Duration
+	// как часто отправлять состояние плеера на сервер
+	HeartbeatInterval time.Duration
+
+	// скачивать в фоне всю библиотеку, а не только ближайшие треки
+	PrefetchLibrary bool
+	// сколько треков скачивать одновременно
+	DownloadConcurrency int
+	// лимит кеша mp3 в байтах, 0 - по умолчанию, отрицательный - без лимита
+	CacheMaxSize int64
+	// лимит скорости скачивания в байтах в секунду, 0 - без лимита
+	DownloadRateLimit int64
+	// лимитный трафик: качать только MeteredTracks ближайших треков, без предзагрузки
+	MeteredNetwork bool
+	MeteredTracks  int
+	// запасные CDN по порядку, если основной из музыкальных настроек недоступен
+	CdnMirrors []string
+}
+
+func (c Config) withDefaults() Config {
+	if c.AdMaxDelay == 0 {
+		c.AdMaxDelay = defaultAdMaxDelay
+	}
+
+	if c.SyncInterval <= 0 {
+		c.SyncInterval = defaultSyncInterval
+	}
+
+	if c.TimeSyncInterval <= 0 {
+		c.TimeSyncInterval = defaultTimeSyncInterval
+	}
+
+	if c.ClockDriftThreshold <= 0 {
+		c.ClockDriftThreshold = defaultClockDriftThreshold
+	}
+
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = defaultHeartbeatInterval
+	}
+
+	if c.ArtistSeparation == 0 {
+		c.ArtistSeparation = defaultArtistSeparation
+	}
+
+	if c.TrackRepeatInterval == 0 {
+		c.TrackRepeatInterval = defaultTrackRepeatInterval
+	}
+
+	if c.DownloadConcurrency <= 0 {
+		c.DownloadConcurrency = defaultDownloadConcurrency
+	}
+
+	if c.CacheMaxSize == 0 {
+		c.CacheMaxSize = defaultCacheMaxSize
+	}
+
+	if c.MeteredTracks <= 0 {
+		c.MeteredTracks = defaultMeteredTracks
+	}
+
+	return c
 }
 
 type CallbackMain interface {
@@ -30,3 +135,25 @@ type CallbackLogin interface {
 	SendErrorMessage(message string)
 	SendCodeIncorrectErrorMessage(message string)
 }
+
+// CallbackCache - покрытие библиотеки кешем и занятое кешем место (limitBytes < 0 - без лимита).
+type CallbackCache interface {
+	SendCacheCoverage(cachedTracks int, totalTracks int, cachedBytes int64, usageBytes int64, limitBytes int64)
+}
+
+// CallbackVenue - открыто ли заведение, opensAtUnix - когда откроется (0 - неизвестно).
+type CallbackVenue interface {
+	SendVenueStatus(closed bool, opensAtUnix int64)
+}
+
+// PlayerOutput - нативный плеер хоста. id - номер воспроизведения.
+// При кроссфейде играют два воспроизведения, громкость (0..1) плавно меняет Ramp.
+// SetVolume плавно меняет общую громкость по расписанию, она умножается на громкость воспроизведений.
+type PlayerOutput interface {
+	Play(id int, filePath string, offsetMs int64) error
+	Ramp(id int, fromVolume, toVolume float64, durationMs int64) error
+	SetVolume(volume float64, durationMs int64) error
+	Pause() error
+	Resume() error
+	Stop(id int) error
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"context"
+)
+
+type downloaderService interface {
+	SetRateLimit(bytesPerSecond int64)
+	SetMetered(metered bool)
+	SetCdnMirrors(mirrors []string)
+	ClearCache(ctx context.Context) (int, error)
+	Run(ctx context.Context) error
+}
+
+// SetDownloadRateLimit - лимит скорости скачивания треков в байтах в секунду, 0 - без лимита.
+func (a *App) SetDownloadRateLimit(bytesPerSecond int64) { a.downloader.SetRateLimit(bytesPerSecond) }
+
+// SetMeteredNetwork - лимитный трафик: качаются только ближайшие треки, предзагрузка на паузе.
+func (a *App) SetMeteredNetwork(metered bool) { a.downloader.SetMetered(metered) }
+
+// SetCdnMirrors - запасные CDN по порядку.
+func (a *App) SetCdnMirrors(mirrors []string) { a.downloader.SetCdnMirrors(mirrors) }
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/utils"
+)
+
+// deviceStatus собирает состояние плеера для heartbeat.
+func (a *App) deviceStatus() *domain.DeviceStatus {
+	status := &domain.DeviceStatus{
+		AppVersion:    a.config.AppVersion,
+		At:            time.Now(),
+		Uptime:        time.Since(a.startedAt),
+		FreeDiskBytes: -1,
+		ClockOffset:   a.timeSync.Offset(),
+	}
+
+	a.state.Player.RLock()
+	status.PlayerStatus = a.state.Player.Status()
+
+	if current := a.state.Player.Current(); current != nil {
+		status.Current = deviceStatusTrack(current)
+		status.Current.Position = a.state.Player.Position()
+	}
+	a.state.Player.RUnlock()
+
+	a.state.Playlist.RLock()
+	for _, item := range a.state.Playlist.Get() {
+		status.Playlist = append(status.Playlist, deviceStatusTrack(item))
+	}
+	a.state.Playlist.RUnlock()
+
+	a.state.Venue.RLock()
+	status.VenueClosed = a.state.Venue.Closed()
+	a.state.Venue.RUnlock()
+
+	a.state.Cache.RLock()
+	status.Cache = a.state.Cache.Coverage()
+	a.state.Cache.RUnlock()
+
+	if free, err := utils.FreeDiskSpace(a.config.CacheDir); err != nil {
+		a.logger.Debug().Err(err).Msg("free disk space unknown")
+	} else {
+		status.FreeDiskBytes = free
+	}
+
+	a.em.Lock()
+	status.LastError, status.LastErrorAt = a.lastError, a.lastErrorAt
+	a.em.Unlock()
+
+	return status
+}
+
+func deviceStatusTrack(item *domain.PlaylistTrack) *domain.DeviceStatusTrack {
+	return &domain.DeviceStatusTrack{
+		TrackID:          item.Track.ID,
+		Type:             item.Type,
+		Duration:         item.Track.Duration,
+		DownloadProgress: item.DownloadProgress,
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/services/player"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+// historyRecorder запоминает проигранные фоновые треки для правил разнесения
+// и сохраняет историю на устройстве, не задерживая плеер.
+type historyRecorder struct {
+	dirty chan struct{}
+	// fm не дает сохранению записать историю поверх Clear
+	fm sync.Mutex
+
+	state  *state.State
+	repo   domain.HistoryRepository
+	logger zerolog.Logger
+}
+
+func newHistoryRecorder(state *state.State, repo domain.HistoryRepository, logger zerolog.Logger) *historyRecorder {
+	return &historyRecorder{
+		dirty:  make(chan struct{}, 1),
+		state:  state,
+		repo:   repo,
+		logger: logger.With().Str("component", "historyRecorder").Logger(),
+	}
+}
+
+// Load восстанавливает историю, сохраненную до перезапуска.
+func (h *historyRecorder) Load(ctx context.Context) {
+	entries, err := h.repo.Get(ctx)
+
+	if err != nil {
+		h.logger.Err(err).Msg("history load fail")
+		return
+	}
+
+	h.state.History.Lock()
+	h.state.History.Set(entries)
+	h.state.History.Unlock()
+
+	h.logger.Debug().Int("entries", len(entries)).Msg("history loaded")
+}
+
+func (h *historyRecorder) TrackStarted(item *domain.PlaylistTrack, at time.Time) {
+	if item.Type == domain.PlaylistTrackTypeAd {
+		return
+	}
+
+	h.state.History.Lock()
+	h.state.History.Add(&domain.HistoryEntry{TrackID: item.Track.ID, ArtistID: item.Track.Artist.ID, PlayedAt: at})
+	h.state.History.Unlock()
+
+	select {
+	case h.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (h *historyRecorder) TrackFinished(*domain.PlaylistTrack, player.Finish) {}
+
+func (h *historyRecorder) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-h.dirty:
+			h.save(ctx)
+		}
+	}
+}
+
+// Clear забывает историю в памяти и на устройстве, например после выхода.
+func (h *historyRecorder) Clear(ctx context.Context) error {
+	h.fm.Lock()
+	defer h.fm.Unlock()
+
+	h.state.History.Lock()
+	h.state.History.Set(nil)
+	h.state.History.Unlock()
+
+	if err := h.repo.Delete(ctx); err != nil {
+		return fmt.Errorf("cannot delete history: %w", err)
+	}
+
+	return nil
+}
+
+func (h *historyRecorder) save(ctx context.Context) {
+	h.fm.Lock()
+	defer h.fm.Unlock()
+
+	h.state.History.RLock()
+	entries := h.state.History.Entries()
+	h.state.History.RUnlock()
+
+	if err := h.repo.Set(ctx, entries); err != nil {
+		h.logger.Err(err).Msg("history save fail")
+	}
+}
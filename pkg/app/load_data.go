@@ -28,7 +28,7 @@ func (a *App) LoadData(ctx context.Context, callback CallbackLoadData) {
 		a.logger.Debug().Interface("auth", auth).
 			Msg("auth get from repo success, set to api client...")
 
-		a.apiClient.SetAuthToken(auth.Token)
+		a.setAuthorized(auth.Token)
 	} else {
 		a.logger.Debug().Msg("auth not set, show login screen...")
 		a.showScreen(ScreenLogin)
@@ -43,8 +43,8 @@ func (a *App) LoadData(ctx context.Context, callback CallbackLoadData) {
 		default:
 			callback.SendErrorMessage(a.errMessageForClient(err))
 
+		// экран входа уже показан в onUnauthorized
 		case *api.UnauthorizedError:
-			a.showScreen(ScreenLogin)
 		}
 
 		return
@@ -58,7 +58,14 @@ func (a *App) LoadData(ctx context.Context, callback CallbackLoadData) {
 		callback.SendErrorMessage(a.errMessageForClient(err))
 	}
 
-	a.showScreen(ScreenPlayer)
+	a.cbm.Lock()
+	a.dataLoaded = true
+	a.cbm.Unlock()
+
+	a.showScreen(a.playerScreen())
+
+	// после выхода плеер остановлен
+	a.player.Play()
 }
 
 func (a *App) loadDataFromAPI(ctx context.Context, callback CallbackLoadData) error {
@@ -123,6 +130,11 @@ func (a *App) awaitLoadFirstTrack(ctx context.Context, callback CallbackLoadData
 			return ctx.Err()
 
 		case <-t.C:
+			// закрытое заведение треки не качает
+			if a.venueClosed() {
+				return nil
+			}
+
 			a.state.Playlist.RLock()
 			progress := a.state.Playlist.FirstItemDownloadProgress()
 
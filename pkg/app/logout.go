@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// на отправку отчетов и отзыв токена при выходе, без связи выход не ждет дольше
+const logoutRequestTimeout = 10 * time.Second
+
+// Logout отвязывает устройство от заведения: токен отзывается на сервере, данные заведения
+// удаляются, показывается экран входа. clearCache - удалить и скачанные треки.
+// Без связи выход выполняется только на устройстве.
+func (a *App) Logout(ctx context.Context, clearCache bool) {
+	if err := a.logout(ctx, clearCache); err != nil {
+		a.logger.Err(err).Msg("logout fail")
+	}
+}
+
+func (a *App) logout(ctx context.Context, clearCache bool) error {
+	a.logger.Info().Bool("clearCache", clearCache).Msg("logout...")
+
+	wasAuthorized, _ := a.auth.get()
+
+	// остановка дописывает в отчет последний трек
+	a.player.Stop()
+
+	reqCtx, cancel := context.WithTimeout(ctx, logoutRequestTimeout)
+
+	// после отзыва токена отчеты этого заведения уже не отправить
+	if err := a.playLog.Flush(reqCtx); err != nil {
+		a.logger.Warn().Err(err).Msg("play log flush before logout fail")
+	}
+
+	if err := a.loginRepo.Logout(reqCtx); err != nil {
+		a.logger.Warn().Err(err).Msg("token revoke fail, logout on device only")
+	}
+
+	cancel()
+
+	var errs []error
+
+	dropped, err := a.dropAuth(ctx)
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// авторизация была сброшена раньше или еще не загружена, файл удаляется в любом случае
+	if !dropped {
+		if err := a.authRepo.Delete(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("cannot delete auth: %w", err))
+		}
+	}
+
+	a.state.Reset()
+
+	if err := a.playerInfoLocalRepo.Delete(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := a.musicDataLocalRepo.Delete(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := a.history.Clear(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := a.playLog.Clear(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if clearCache {
+		removed, err := a.downloader.ClearCache(ctx)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot clear cache: %w", err))
+		} else {
+			a.logger.Info().Int("removed", removed).Msg("cache cleared on logout")
+		}
+	}
+
+	// при 401 во время выхода экран входа уже показан в onUnauthorized
+	if dropped || !wasAuthorized {
+		a.showScreen(ScreenLogin)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("logout incomplete: %v", errs)
+	}
+
+	a.logger.Info().Msg("logout success")
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/domain/repositories"
+)
+
+func Test_Logout(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	srv := httptest.NewServer(apiHandler(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":null}`))
+	}))
+	defer srv.Close()
+
+	a, callback, cleanup := newTestApp(t, srv.URL)
+	defer cleanup()
+
+	ctx := context.Background()
+	dataDir, key := a.config.DataDir, a.config.SecretKey
+	playedAt := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	item := &domain.PlaylistTrack{Track: &domain.Track{ID: 1}, Type: domain.PlaylistTrackTypeBackground}
+
+	if err := a.authRepo.Set(ctx, &domain.Auth{PlayerID: 1, Token: "valid"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.playerInfoLocalRepo.Set(ctx, &domain.PlayerInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.musicDataLocalRepo.Set(ctx, &domain.MusicData{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// неотправленный отчет сохранен до выхода
+	if err := repositories.NewPlayLogFileRepo(path.Join(dataDir, "pl.dat"), key).Set(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	a.setAuthorized("valid")
+	a.history.TrackStarted(item, playedAt)
+	a.history.save(ctx)
+	a.playLog.TrackStarted(item, playedAt)
+
+	a.state.PlayerInfo.Set(&domain.PlayerInfo{})
+	a.state.MusicData.Set(&domain.MusicData{})
+	a.state.Playlist.Append(item)
+	a.state.Venue.Set(true, playedAt)
+
+	a.Logout(ctx, false)
+
+	wantCalls := []string{"POST /player/play-log Bearer valid", "POST /auth/logout Bearer valid"}
+
+	mu.Lock()
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("got calls %q, want %q", calls, wantCalls)
+	}
+	mu.Unlock()
+
+	for _, name := range []string{"a.tk", "pi.dat", "md.dat", "h.dat", "pl.dat"} {
+		if _, err := os.Stat(path.Join(dataDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s not removed: %v", name, err)
+		}
+	}
+
+	if a.state.PlayerInfo.Get() != nil || a.state.MusicData.Get() != nil || len(a.state.Playlist.Get()) != 0 ||
+		len(a.state.History.Entries()) != 0 || a.state.Venue.Closed() {
+		t.Errorf("state not reset")
+	}
+
+	if authorized, _ := a.auth.get(); authorized {
+		t.Errorf("still authorized")
+	}
+
+	if got := callback.count(ScreenLogin); got != 1 {
+		t.Errorf("got login screen shown %d times, want 1", got)
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/services/player"
+)
+
+type PlayerController interface {
+	Play()
+	Pause()
+	Next()
+	Stop()
+}
+
+type playerService interface {
+	PlayerController
+	SetVolume(volume float64)
+	AddObserver(observer player.Observer)
+	Run(ctx context.Context) error
+}
+
+func (a *App) Play()  { a.player.Play() }
+func (a *App) Pause() { a.player.Pause() }
+func (a *App) Next()  { a.player.Next() }
+func (a *App) Stop()  { a.player.Stop() }
+
+func (a *App) newPlayerOutput() player.Output {
+	if a.playerOutput == nil {
+		a.logger.Warn().Msg("PlayerOutput is nil, null output used")
+
+		return player.NewNullOutput()
+	}
+
+	return &playerOutput{output: a.playerOutput}
+}
+
+// playerOutput адаптирует PlayerOutput хоста к player.Output.
+type playerOutput struct {
+	output PlayerOutput
+}
+
+func (p *playerOutput) Play(id int, item *domain.PlaylistTrack, offset time.Duration) error {
+	return p.output.Play(id, item.FilePath, offset.Milliseconds())
+}
+
+func (p *playerOutput) Ramp(id int, from, to float64, d time.Duration) error {
+	return p.output.Ramp(id, from, to, d.Milliseconds())
+}
+
+func (p *playerOutput) SetVolume(volume float64, d time.Duration) error {
+	return p.output.SetVolume(volume, d.Milliseconds())
+}
+
+func (p *playerOutput) Pause() error      { return p.output.Pause() }
+func (p *playerOutput) Resume() error     { return p.output.Resume() }
+func (p *playerOutput) Stop(id int) error { return p.output.Stop(id) }
+
+// cacheTouchObserver отмечает время проигрывания трека в кеше для вытеснения.
+// Индекс пишется на диск в Run, не задерживая плеер.
+type cacheTouchObserver struct {
+	mu sync.Mutex
+	// время проигрывания по ID трека, еще не записанное в индекс
+	pending map[int]time.Time
+	dirty   chan struct{}
+
+	index  domain.CacheIndex
+	logger zerolog.Logger
+}
+
+func newCacheTouchObserver(index domain.CacheIndex, logger zerolog.Logger) *cacheTouchObserver {
+	return &cacheTouchObserver{
+		pending: make(map[int]time.Time),
+		dirty:   make(chan struct{}, 1),
+		index:   index,
+		logger:  logger.With().Str("component", "cacheTouchObserver").Logger(),
+	}
+}
+
+func (c *cacheTouchObserver) TrackStarted(item *domain.PlaylistTrack, at time.Time) {
+	c.mu.Lock()
+	c.pending[item.Track.ID] = at
+	c.mu.Unlock()
+
+	select {
+	case c.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (c *cacheTouchObserver) TrackFinished(*domain.PlaylistTrack, player.Finish) {}
+
+func (c *cacheTouchObserver) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-c.dirty:
+			c.flush()
+		}
+	}
+}
+
+func (c *cacheTouchObserver) flush() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[int]time.Time)
+	c.mu.Unlock()
+
+	for trackID, at := range pending {
+		if err := c.index.Touch(trackID, at); err != nil {
+			c.logger.Warn().Err(err).Int("trackId", trackID).Msg("cache touch fail")
+		}
+	}
+}
+
+// serverTimeObserver переводит время событий плеера на часы сервера: плейлистер сверяет
+// историю по ним, и отчеты о проигрывании не должны зависеть от часов устройства.
+// Сам плеер идет по часам устройства, чтобы синхронизация не сдвигала позицию трека.
+type serverTimeObserver struct {
+	observer player.Observer
+	offset   func() time.Duration
+}
+
+func (s *serverTimeObserver) TrackStarted(item *domain.PlaylistTrack, at time.Time) {
+	s.observer.TrackStarted(item, at.Add(s.offset()))
+}
+
+func (s *serverTimeObserver) TrackFinished(item *domain.PlaylistTrack, finish player.Finish) {
+	finish.At = finish.At.Add(s.offset())
+
+	s.observer.TrackFinished(item, finish)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/cache"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/services/player"
+)
+
+type recordingObserver struct {
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+func (r *recordingObserver) TrackStarted(_ *domain.PlaylistTrack, at time.Time) {
+	r.startedAt = at
+}
+
+func (r *recordingObserver) TrackFinished(_ *domain.PlaylistTrack, finish player.Finish) {
+	r.finishedAt = finish.At
+}
+
+func Test_serverTimeObserver(t *testing.T) {
+	rec := &recordingObserver{}
+	// часы устройства отстают на 6 часов
+	o := &serverTimeObserver{observer: rec, offset: func() time.Duration { return 6 * time.Hour }}
+
+	deviceNow := time.Date(2021, time.March, 1, 6, 0, 0, 0, time.UTC)
+	item := &domain.PlaylistTrack{Track: &domain.Track{ID: 1}}
+
+	o.TrackStarted(item, deviceNow)
+	o.TrackFinished(item, player.Finish{At: deviceNow.Add(3 * time.Minute)})
+
+	if want := deviceNow.Add(6 * time.Hour); !rec.startedAt.Equal(want) {
+		t.Errorf("got startedAt %s, want %s", rec.startedAt, want)
+	}
+
+	if want := deviceNow.Add(6*time.Hour + 3*time.Minute); !rec.finishedAt.Equal(want) {
+		t.Errorf("got finishedAt %s, want %s", rec.finishedAt, want)
+	}
+}
+
+func Test_cacheTouchObserver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "app")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	index := cache.NewFileIndex(dir, zerolog.Nop())
+
+	if err := ioutil.WriteFile(index.FilePath(1), []byte("mp3 data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := index.Put(&domain.CacheEntry{TrackID: 1, FileName: "1", Size: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCacheTouchObserver(index, zerolog.Nop())
+	playedAt := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// плеер не ждет записи индекса
+	c.TrackStarted(&domain.PlaylistTrack{Track: &domain.Track{ID: 1}}, playedAt)
+
+	if e, _ := index.Entry(1); !e.LastPlayedAt.IsZero() {
+		t.Fatalf("index written synchronously")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		_ = c.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if e, _ := index.Entry(1); e.LastPlayedAt.Equal(playedAt) {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if e, _ := index.Entry(1); !e.LastPlayedAt.Equal(playedAt) {
+		t.Errorf("got lastPlayedAt %s, want %s", e.LastPlayedAt, playedAt)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// в лог уходит не больше этого хвоста файла
+const maxLogUploadSize = 1 << 20
+
+// remoteController выполняет команды с сервера.
+type remoteController struct {
+	app *App
+}
+
+func (r *remoteController) Skip()                    { r.app.player.Next() }
+func (r *remoteController) Pause()                   { r.app.player.Pause() }
+func (r *remoteController) Resume()                  { r.app.player.Play() }
+func (r *remoteController) SetVolume(volume float64) { r.app.player.SetVolume(volume) }
+
+func (r *remoteController) ReloadMusicData(ctx context.Context) error {
+	return r.app.syncer.Reload(ctx)
+}
+
+func (r *remoteController) ClearCache(ctx context.Context) error {
+	removed, err := r.app.downloader.ClearCache(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	r.app.logger.Info().Int("removed", removed).Msg("cache cleared by remote command")
+
+	return nil
+}
+
+func (r *remoteController) UploadLogs(ctx context.Context) error {
+	return r.app.uploadLogs(ctx)
+}
+
+// Logout не трогает кеш треков. Контекст команды отменится вместе с авторизацией,
+// поэтому выход доводится до конца в своем.
+func (r *remoteController) Logout(context.Context) error {
+	return r.app.logout(context.Background(), false)
+}
+
+var errNoLogFile = errors.New("log is not written to file")
+
+// uploadLogs отправляет на сервер хвост лога текущего запуска.
+func (a *App) uploadLogs(ctx context.Context) error {
+	if a.logFilePath == "" {
+		return errNoLogFile
+	}
+
+	file, err := os.Open(a.logFilePath)
+
+	if err != nil {
+		return fmt.Errorf("cannot os.Open: %w, filePath: %s", err, a.logFilePath)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return fmt.Errorf("cannot file.Stat: %w, filePath: %s", err, a.logFilePath)
+	}
+
+	if offset := info.Size() - maxLogUploadSize; offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("cannot file.Seek: %w, filePath: %s", err, a.logFilePath)
+		}
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(file, maxLogUploadSize))
+
+	if err != nil {
+		return fmt.Errorf("cannot read log: %w, filePath: %s", err, a.logFilePath)
+	}
+
+	return a.logSender.Send(ctx, path.Base(a.logFilePath), content)
+}
@@ -0,0 +1,46 @@
+package app
+
+import "time"
+
+func (a *App) RegisterVenueCallback(callback CallbackVenue) {
+	a.cbm.Lock()
+	a.callbackVenue = callback
+	a.cbm.Unlock()
+}
+
+// onVenueChanged - заведение закрылось или открылось, плеер и скачивание следят за этим сами.
+func (a *App) onVenueChanged(closed bool, opensAt time.Time) {
+	a.cbm.RLock()
+	callback, dataLoaded := a.callbackVenue, a.dataLoaded
+	a.cbm.RUnlock()
+
+	if callback != nil {
+		var opensAtUnix int64
+
+		if !opensAt.IsZero() {
+			opensAtUnix = opensAt.Unix()
+		}
+
+		callback.SendVenueStatus(closed, opensAtUnix)
+	}
+
+	// до загрузки данных экран выбирает LoadData
+	if dataLoaded {
+		a.showScreen(a.playerScreen())
+	}
+}
+
+func (a *App) playerScreen() string {
+	if a.venueClosed() {
+		return ScreenClosed
+	}
+
+	return ScreenPlayer
+}
+
+func (a *App) venueClosed() bool {
+	a.state.Venue.RLock()
+	defer a.state.Venue.RUnlock()
+
+	return a.state.Venue.Closed()
+}
@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const indexFileName = "index.json"
+
+// fileIndex хранит индекс скачанных треков в JSON-файле рядом с самими файлами.
+type fileIndex struct {
+	mu      sync.Mutex
+	entries map[int]*domain.CacheEntry
+
+	dir    string
+	logger zerolog.Logger
+}
+
+func NewFileIndex(dir string, logger zerolog.Logger) *fileIndex {
+	return &fileIndex{
+		entries: make(map[int]*domain.CacheEntry),
+		dir:     dir,
+		logger:  logger.With().Str("component", "cacheIndex").Logger(),
+	}
+}
+
+// Load читает индекс. Записи без файла, с другим размером или контрольной суммой удаляются.
+// Файлы без записи индекс не добавляет: их полноту проверяет загрузчик.
+// Файлы хешируются без блокировки индекса, чтобы не задерживать плеер.
+func (f *fileIndex) Load() error {
+	rawData, err := ioutil.ReadFile(f.indexPath())
+
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read index: %w", err)
+	}
+
+	var entries []*domain.CacheEntry
+
+	if len(rawData) > 0 {
+		if err := json.Unmarshal(rawData, &entries); err != nil {
+			f.logger.Warn().Err(err).Msg("index corrupted, rebuilding")
+		}
+	}
+
+	loaded := make(map[int]*domain.CacheEntry, len(entries))
+
+	for _, e := range entries {
+		if err := f.verify(e); err != nil {
+			f.logger.Warn().Err(err).Interface("entry", e).Msg("stale entry dropped")
+			f.removeFile(e)
+
+			continue
+		}
+
+		loaded[e.TrackID] = e
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// скачанное, пока шла загрузка
+	for trackID, e := range f.entries {
+		loaded[trackID] = e
+	}
+
+	f.entries = loaded
+
+	f.logger.Debug().Int("entries", len(f.entries)).Msg("index loaded")
+
+	return f.save()
+}
+
+// verify сверяет файл записи с размером и контрольной суммой, если она есть.
+func (f *fileIndex) verify(e *domain.CacheEntry) error {
+	filePath := path.Join(f.dir, e.FileName)
+	fi, err := os.Stat(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	if fi.Size() != e.Size {
+		//nolint:goerr113
+		return fmt.Errorf("size mismatch: %d", fi.Size())
+	}
+
+	if e.Checksum == "" {
+		return nil
+	}
+
+	checksum, err := fileChecksum(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	if checksum != e.Checksum {
+		//nolint:goerr113
+		return fmt.Errorf("checksum mismatch: %s", checksum)
+	}
+
+	return nil
+}
+
+// Lookup проверяет только наличие и размер файла: контрольные суммы сверяются в Load,
+// а Lookup вызывается под блокировкой плейлиста. Испорченный файл удаляется вместе с записью.
+func (f *fileIndex) Lookup(trackID int, url string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.entries[trackID]
+
+	if !ok {
+		return "", false
+	}
+
+	if e.URL != url {
+		f.logger.Debug().Int("trackId", trackID).Str("url", url).Str("entryUrl", e.URL).Msg("track url changed")
+		f.drop(e)
+
+		return "", false
+	}
+
+	filePath := path.Join(f.dir, e.FileName)
+	fi, err := os.Stat(filePath)
+
+	if err != nil || fi.Size() != e.Size {
+		f.logger.Warn().Err(err).Interface("entry", e).Msg("cached file missing or truncated")
+		f.drop(e)
+
+		return "", false
+	}
+
+	return filePath, true
+}
+
+func (f *fileIndex) Entry(trackID int) (*domain.CacheEntry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.entries[trackID]
+
+	if !ok {
+		return nil, false
+	}
+
+	entry := *e
+
+	return &entry, true
+}
+
+// Put добавляет запись, контрольная сумма считается, если не задана.
+func (f *fileIndex) Put(entry *domain.CacheEntry) error {
+	if entry.Checksum == "" {
+		checksum, err := fileChecksum(path.Join(f.dir, entry.FileName))
+
+		if err != nil {
+			return err
+		}
+
+		entry.Checksum = checksum
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries[entry.TrackID] = entry
+
+	return f.save()
+}
+
+func (f *fileIndex) Remove(trackID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.entries[trackID]
+
+	if !ok {
+		return nil
+	}
+
+	delete(f.entries, trackID)
+
+	if err := os.Remove(path.Join(f.dir, e.FileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove file: %w, fileName: %s", err, e.FileName)
+	}
+
+	return f.save()
+}
+
+func (f *fileIndex) Touch(trackID int, playedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.entries[trackID]
+
+	if !ok {
+		return nil
+	}
+
+	e.LastPlayedAt = playedAt
+
+	return f.save()
+}
+
+func (f *fileIndex) Entries() []*domain.CacheEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries := make([]*domain.CacheEntry, 0, len(f.entries))
+
+	for _, e := range f.entries {
+		entry := *e
+		entries = append(entries, &entry)
+	}
+
+	return entries
+}
+
+func (f *fileIndex) Usage() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var usage int64
+
+	for _, e := range f.entries {
+		usage += e.Size
+	}
+
+	return usage
+}
+
+func (f *fileIndex) FilePath(trackID int) string {
+	return path.Join(f.dir, strconv.Itoa(trackID))
+}
+
+func (f *fileIndex) drop(e *domain.CacheEntry) {
+	delete(f.entries, e.TrackID)
+
+	f.removeFile(e)
+
+	if err := f.save(); err != nil {
+		f.logger.Err(err).Msg("index save fail")
+	}
+}
+
+func (f *fileIndex) removeFile(e *domain.CacheEntry) {
+	if err := os.Remove(path.Join(f.dir, e.FileName)); err != nil && !os.IsNotExist(err) {
+		f.logger.Err(err).Interface("entry", e).Msg("remove file fail")
+	}
+}
+
+// save атомарно записывает индекс. Вызывать под f.mu.
+func (f *fileIndex) save() error {
+	entries := make([]*domain.CacheEntry, 0, len(f.entries))
+
+	for _, e := range f.entries {
+		entries = append(entries, e)
+	}
+
+	rawData, err := json.Marshal(entries)
+
+	if err != nil {
+		return fmt.Errorf("cannot marshal index: %w", err)
+	}
+
+	tmpPath := f.indexPath() + ".tmp"
+
+	if err := ioutil.WriteFile(tmpPath, rawData, 0600); err != nil {
+		return fmt.Errorf("cannot write index: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, f.indexPath()); err != nil {
+		return fmt.Errorf("cannot rename index: %w", err)
+	}
+
+	return nil
+}
+
+func (f *fileIndex) indexPath() string {
+	return path.Join(f.dir, indexFileName)
+}
+
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return "", fmt.Errorf("cannot open file: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("cannot read file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+func newTestIndex(t *testing.T) (*fileIndex, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cache")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewFileIndex(dir, zerolog.Nop()), func() { _ = os.RemoveAll(dir) }
+}
+
+func writeTrackFile(t *testing.T, f *fileIndex, trackID int, data string) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(f.FilePath(trackID), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_fileIndex(t *testing.T) {
+	t.Run("put and lookup after reload", func(t *testing.T) {
+		f, cleanup := newTestIndex(t)
+		defer cleanup()
+
+		writeTrackFile(t, f, 1, "mp3 data")
+
+		if err := f.Put(&domain.CacheEntry{TrackID: 1, FileName: "1", URL: "u1", Size: 8}); err != nil {
+			t.Fatal(err)
+		}
+
+		reloaded := NewFileIndex(f.dir, zerolog.Nop())
+
+		if err := reloaded.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		if filePath, ok := reloaded.Lookup(1, "u1"); !ok || filePath != f.FilePath(1) {
+			t.Errorf("got %q, %v", filePath, ok)
+		}
+	})
+
+	t.Run("truncated file dropped", func(t *testing.T) {
+		f, cleanup := newTestIndex(t)
+		defer cleanup()
+
+		writeTrackFile(t, f, 1, "mp3 data")
+
+		if err := f.Put(&domain.CacheEntry{TrackID: 1, FileName: "1", URL: "u1", Size: 8}); err != nil {
+			t.Fatal(err)
+		}
+
+		writeTrackFile(t, f, 1, "mp3")
+
+		if _, ok := f.Lookup(1, "u1"); ok {
+			t.Errorf("truncated file found")
+		}
+
+		if _, err := os.Stat(f.FilePath(1)); !os.IsNotExist(err) {
+			t.Errorf("truncated file not removed: %v", err)
+		}
+	})
+
+	t.Run("corrupted file dropped on load", func(t *testing.T) {
+		f, cleanup := newTestIndex(t)
+		defer cleanup()
+
+		writeTrackFile(t, f, 1, "mp3 data")
+
+		if err := f.Put(&domain.CacheEntry{TrackID: 1, FileName: "1", URL: "u1", Size: 8}); err != nil {
+			t.Fatal(err)
+		}
+
+		writeTrackFile(t, f, 1, "mp3 dat?")
+
+		reloaded := NewFileIndex(f.dir, zerolog.Nop())
+
+		if err := reloaded.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := reloaded.Entry(1); ok {
+			t.Errorf("corrupted file kept in index")
+		}
+
+		if _, err := os.Stat(f.FilePath(1)); !os.IsNotExist(err) {
+			t.Errorf("corrupted file not removed: %v", err)
+		}
+	})
+
+	t.Run("url changed", func(t *testing.T) {
+		f, cleanup := newTestIndex(t)
+		defer cleanup()
+
+		writeTrackFile(t, f, 1, "mp3 data")
+
+		if err := f.Put(&domain.CacheEntry{TrackID: 1, FileName: "1", URL: "u1", Size: 8}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := f.Lookup(1, "u2"); ok {
+			t.Errorf("file with old url found")
+		}
+	})
+
+	t.Run("files without entry not adopted", func(t *testing.T) {
+		f, cleanup := newTestIndex(t)
+		defer cleanup()
+
+		writeTrackFile(t, f, 1, "mp3 data")
+
+		if err := f.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := f.Entry(1); ok {
+			t.Errorf("file without entry adopted")
+		}
+
+		if _, err := os.Stat(f.FilePath(1)); err != nil {
+			t.Errorf("file without entry removed: %v", err)
+		}
+	})
+}
@@ -6,8 +6,13 @@ type (
 		Title string
 		Times AdTimes
 		Track *Track
+		// насколько реклама громче фона, прибавляется к громкости интервала
+		VolumeBoost float64
 	}
 
+	// AdTimes - расписание рекламы.
+	// Days - дни недели по ISO-8601 (1 - понедельник, 7 - воскресенье), пустой список - каждый день.
+	// Times - секунды от начала суток.
 	AdTimes struct {
 		Days  []int
 		Times []int
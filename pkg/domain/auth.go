@@ -11,5 +11,6 @@ type (
 	AuthRepository interface {
 		Set(ctx context.Context, auth *Auth) error
 		Get(ctx context.Context) (*Auth, error)
+		Delete(ctx context.Context) error
 	}
 )
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+type (
+	// CacheCoverage - сколько треков библиотеки (музыка и реклама) скачано на устройство.
+	CacheCoverage struct {
+		CachedTracks int
+		TotalTracks  int
+		CachedBytes  int64
+
+		// занято всем кешем и лимит (отрицательный - без лимита)
+		UsageBytes int64
+		LimitBytes int64
+	}
+
+	// CacheEntry - скачанный файл трека.
+	CacheEntry struct {
+		TrackID  int
+		FileName string
+		// Track.MP3URL, с которого скачан файл
+		URL  string
+		Size int64
+		ETag string
+		// sha256 в hex
+		Checksum     string
+		DownloadedAt time.Time
+		LastPlayedAt time.Time
+	}
+
+	// CacheIndex - индекс скачанных файлов треков.
+	CacheIndex interface {
+		Load() error
+		// Lookup возвращает путь к полному файлу трека, скачанному с url.
+		Lookup(trackID int, url string) (string, bool)
+		Entry(trackID int) (*CacheEntry, bool)
+		Put(entry *CacheEntry) error
+		Remove(trackID int) error
+		Touch(trackID int, playedAt time.Time) error
+		Entries() []*CacheEntry
+		Usage() int64
+		FilePath(trackID int) string
+	}
+)
+
+// LastUsedAt - время последнего использования файла для вытеснения из кеша.
+func (e *CacheEntry) LastUsedAt() time.Time {
+	if e.LastPlayedAt.After(e.DownloadedAt) {
+		return e.LastPlayedAt
+	}
+
+	return e.DownloadedAt
+}
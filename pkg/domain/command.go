@@ -0,0 +1,44 @@
+package domain
+
+import "context"
+
+type CommandType string
+
+const (
+	CommandSkip            CommandType = "skip"
+	CommandPause           CommandType = "pause"
+	CommandResume          CommandType = "resume"
+	CommandSetVolume       CommandType = "setVolume"
+	CommandReloadMusicData CommandType = "reloadMusicData"
+	CommandClearCache      CommandType = "clearCache"
+	CommandUploadLogs      CommandType = "uploadLogs"
+	CommandLogout          CommandType = "logout"
+)
+
+type (
+	// Command - команда плееру с сервера.
+	Command struct {
+		ID   string
+		Type CommandType
+		// для CommandSetVolume, 0..1
+		Volume *float64
+	}
+
+	// CommandResult - подтверждение выполнения команды.
+	CommandResult struct {
+		CommandID string
+		OK        bool
+		Message   string
+	}
+
+	// CommandChannel - канал команд с сервера (long polling, позже SSE или WebSocket).
+	// Receive ждет новые команды и может вернуть пустой список, если их не было.
+	CommandChannel interface {
+		Receive(ctx context.Context) ([]*Command, error)
+		Ack(ctx context.Context, result *CommandResult) error
+	}
+
+	LogSender interface {
+		Send(ctx context.Context, name string, content []byte) error
+	}
+)
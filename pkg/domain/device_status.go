@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/progress"
+)
+
+type (
+	// DeviceStatus - состояние плеера для службы поддержки.
+	DeviceStatus struct {
+		AppVersion string
+		At         time.Time
+		Uptime     time.Duration
+
+		PlayerStatus PlayerStatus
+		// nil - ничего не играет
+		Current     *DeviceStatusTrack
+		Playlist    []*DeviceStatusTrack
+		VenueClosed bool
+
+		Cache CacheCoverage
+		// -1 - неизвестно
+		FreeDiskBytes int64
+		// насколько часы устройства отстают от серверных
+		ClockOffset time.Duration
+
+		// пустая - ошибок не было
+		LastError   string
+		LastErrorAt time.Time
+	}
+
+	DeviceStatusTrack struct {
+		TrackID  int
+		Type     PlaylistTrackType
+		Duration time.Duration
+		// только для текущего трека
+		Position         time.Duration
+		DownloadProgress progress.Progress
+	}
+
+	HeartbeatSender interface {
+		Send(ctx context.Context, status *DeviceStatus) error
+	}
+)
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// HistoryEntry - проигранный фоновый трек.
+	HistoryEntry struct {
+		TrackID  int
+		ArtistID int
+		PlayedAt time.Time
+	}
+
+	// HistoryRepository - история проигрывания на устройстве, от старых треков к новым.
+	HistoryRepository interface {
+		Get(ctx context.Context) ([]*HistoryEntry, error)
+		Set(ctx context.Context, entries []*HistoryEntry) error
+		Delete(ctx context.Context) error
+	}
+)
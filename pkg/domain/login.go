@@ -10,5 +10,7 @@ type (
 
 	LoginRepository interface {
 		Login(ctx context.Context, code string) (*LoginResponse, error)
+		// Logout отзывает токен и отвязывает устройство от заведения.
+		Logout(ctx context.Context) error
 	}
 )
@@ -15,9 +15,18 @@ type (
 		Start    int
 		End      int
 		TrackIDs []int
+		// громкость интервала 0..1, nil - полная
+		Volume *float64
 	}
 
 	MusicDataRepository interface {
 		Get(ctx context.Context) (*MusicData, error)
 	}
+
+	// MusicDataLocalRepository - последние полученные MusicData на устройстве.
+	MusicDataLocalRepository interface {
+		MusicDataRepository
+		Set(ctx context.Context, musicData *MusicData) error
+		Delete(ctx context.Context) error
+	}
 )
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type PlayLogEvent string
+
+const (
+	PlayLogEventStart  PlayLogEvent = "start"
+	PlayLogEventFinish PlayLogEvent = "finish"
+	PlayLogEventSkip   PlayLogEvent = "skip"
+)
+
+type (
+	// PlayLogEntry - событие проигрывания для отчета о выходах.
+	// ID уникален, по нему сервер отбрасывает повторно отправленные записи.
+	PlayLogEntry struct {
+		ID      string
+		Event   PlayLogEvent
+		At      time.Time
+		TrackID int
+		Type    PlaylistTrackType
+
+		// заполняются только для PlaylistTrackTypeAd
+		AdID          int
+		AdScheduledAt time.Time
+
+		// для finish и skip: сколько трек играл и остановлен ли до конца
+		PlayedFor   time.Duration
+		Interrupted bool
+	}
+
+	// PlayLogRepository - очередь неотправленных записей на устройстве, от старых к новым.
+	PlayLogRepository interface {
+		Get(ctx context.Context) ([]*PlayLogEntry, error)
+		Set(ctx context.Context, entries []*PlayLogEntry) error
+		Delete(ctx context.Context) error
+	}
+
+	PlayLogSender interface {
+		Send(ctx context.Context, entries []*PlayLogEntry) error
+	}
+)
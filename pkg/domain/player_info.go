@@ -12,7 +12,11 @@ type (
 		HasCrossFade      bool
 		CrossFadeDuration time.Duration
 		ServerTime        time.Time
-		DemoAt            *time.Time
+		// IANA-пояс заведения, например Europe/Moscow, пустой - не задан
+		Timezone string
+		// часы работы, пустой список - без ограничений
+		OpeningHours []OpeningHours
+		DemoAt       *time.Time
 
 		Company PlayerInfoCompany
 	}
@@ -34,7 +38,23 @@ type (
 		LogoDarkURL  *string
 	}
 
+	// OpeningHours - часы работы по местному времени заведения.
+	// Days - дни недели по ISO-8601, пустой список - каждый день. Start, End - секунды от начала суток,
+	// Start > End - работа после полуночи, она относится ко дню начала.
+	OpeningHours struct {
+		Days  []int
+		Start int
+		End   int
+	}
+
 	PlayerInfoRepository interface {
 		Get(ctx context.Context) (*PlayerInfo, error)
 	}
+
+	// PlayerInfoLocalRepository - последний полученный PlayerInfo на устройстве.
+	PlayerInfoLocalRepository interface {
+		PlayerInfoRepository
+		Set(ctx context.Context, playerInfo *PlayerInfo) error
+		Delete(ctx context.Context) error
+	}
 )
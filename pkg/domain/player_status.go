@@ -0,0 +1,9 @@
+package domain
+
+type PlayerStatus string
+
+const (
+	PlayerStatusStopped PlayerStatus = "stopped"
+	PlayerStatusPlaying PlayerStatus = "playing"
+	PlayerStatusPaused  PlayerStatus = "paused"
+)
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/qkveri/player_core/pkg/progress"
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/progress"
+)
 
 type PlaylistTrackType string
 
@@ -14,6 +18,10 @@ type PlaylistTrack struct {
 	Type                    PlaylistTrackType
 	BackgroundIntervalIndex int
 
+	// заполняются только для PlaylistTrackTypeAd
+	Ad            *Ad
+	AdScheduledAt time.Time
+
 	DownloadProgress progress.Progress
 	FilePath         string
 }
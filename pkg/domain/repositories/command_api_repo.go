@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// сколько сервер держит запрос без команд, меньше таймаута api.Client
+const commandsLongPollWaitSec = 8
+
+// commandApiRepo - канал команд через long polling.
+type commandApiRepo struct {
+	client api.Client
+}
+
+func NewCommandApiRepo(client api.Client) *commandApiRepo {
+	return &commandApiRepo{
+		client: client,
+	}
+}
+
+func (c *commandApiRepo) Receive(ctx context.Context) ([]*domain.Command, error) {
+	resRaw, err := c.client.GET(ctx, fmt.Sprintf("/player/commands?wait=%d", commandsLongPollWaitSec))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var resCommands []struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		Params struct {
+			Volume *float64 `json:"volume"`
+		} `json:"params"`
+	}
+
+	if err := json.Unmarshal(resRaw, &resCommands); err != nil {
+		return nil, fmt.Errorf("commands response unmarshall fail: %w", err)
+	}
+
+	commands := make([]*domain.Command, 0, len(resCommands))
+
+	for _, cmd := range resCommands {
+		commands = append(commands, &domain.Command{
+			ID:     cmd.ID,
+			Type:   domain.CommandType(cmd.Type),
+			Volume: cmd.Params.Volume,
+		})
+	}
+
+	return commands, nil
+}
+
+func (c *commandApiRepo) Ack(ctx context.Context, result *domain.CommandResult) error {
+	data := struct {
+		ID      string `json:"id"`
+		OK      bool   `json:"ok"`
+		Message string `json:"message,omitempty"`
+	}{
+		ID:      result.CommandID,
+		OK:      result.OK,
+		Message: result.Message,
+	}
+
+	_, err := c.client.POST(ctx, "/player/commands/ack", data)
+
+	return err
+}
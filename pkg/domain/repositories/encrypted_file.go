@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// encryptedFile хранит значение в файле в виде JSON, зашифрованного AES-GCM.
+type encryptedFile struct {
+	filePath string
+	key      []byte
+}
+
+func newEncryptedFile(filePath string, key string) encryptedFile {
+	return encryptedFile{
+		filePath: filePath,
+		key:      []byte(key),
+	}
+}
+
+// write заменяет файл атомарно: данные пишутся во временный файл рядом и переименовываются,
+// поэтому сбой посреди записи оставляет прежнее содержимое.
+func (e encryptedFile) write(v interface{}) error {
+	rawData, err := json.Marshal(v)
+
+	if err != nil {
+		return fmt.Errorf("connot marshal: %w", err)
+	}
+
+	gcm, err := e.createGCM()
+
+	if err != nil {
+		return fmt.Errorf("connot create gcm: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("rand.Read failed: %w", err)
+	}
+
+	cipherData := gcm.Seal(nonce, nonce, rawData, nil)
+
+	tmpPath := e.filePath + ".tmp"
+
+	if err := writeFileSync(tmpPath, cipherData); err != nil {
+		return fmt.Errorf("cannot write to file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, e.filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot rename file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileSync записывает файл и дожидается, пока данные окажутся на диске.
+func writeFileSync(filePath string, data []byte) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// read возвращает false, если файла нет.
+func (e encryptedFile) read(v interface{}) (bool, error) {
+	rawData, err := ioutil.ReadFile(e.filePath)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot read data from file: %w", err)
+	}
+
+	gcm, err := e.createGCM()
+
+	if err != nil {
+		return false, fmt.Errorf("connot create gcm: %w", err)
+	}
+
+	if len(rawData) < gcm.NonceSize() {
+		return false, fmt.Errorf("file too short: %d bytes", len(rawData))
+	}
+
+	nonce, ciphertext := rawData[:gcm.NonceSize()], rawData[gcm.NonceSize():]
+
+	plainData, err := gcm.Open(nil, nonce, ciphertext, nil)
+
+	if err != nil {
+		return false, fmt.Errorf("gcm.Open failed: %w", err)
+	}
+
+	if err := json.Unmarshal(plainData, v); err != nil {
+		return false, fmt.Errorf("connot unmarshal: %w, plainData: %s", err, plainData)
+	}
+
+	return true, nil
+}
+
+// remove удаляет файл, отсутствие файла - не ошибка.
+func (e encryptedFile) remove() error {
+	if err := os.Remove(e.filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove file: %w", err)
+	}
+
+	return nil
+}
+
+func (e encryptedFile) createGCM() (cipher.AEAD, error) {
+	blockCipher, err := aes.NewCipher(e.key)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create chipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(blockCipher)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_encryptedFile_write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repositories")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := newEncryptedFile(path.Join(dir, "pl.dat"), testKey)
+
+	if err := file.write([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// запись, прерванная до переименования, не портит прежний файл
+	if err := os.Mkdir(file.filePath+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.write([]int{1, 2}); err == nil {
+		t.Fatal("got nil error, want write fail")
+	}
+
+	var got []int
+
+	if ok, err := file.read(&got); err != nil || !ok || len(got) != 1 {
+		t.Fatalf("got %v, %v, %v after failed write", got, ok, err)
+	}
+
+	if err := os.Remove(file.filePath + ".tmp"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.write([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := file.read(&got); err != nil || !ok || len(got) != 2 {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+
+	if _, err := os.Stat(file.filePath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left: %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package repositories
+
+import "github.com/qkveri/player_core/pkg/api"
+
+// canFallback - можно ли вместо ответа API взять сохраненные данные.
+// Отозванная авторизация должна дойти до приложения.
+func canFallback(err error) bool {
+	_, unauthorized := err.(*api.UnauthorizedError)
+
+	return !unauthorized
+}
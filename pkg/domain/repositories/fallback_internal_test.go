@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const testKey = "85dea59886138936d3b1a573f6069357"
+
+type fakePlayerInfoRepo struct {
+	playerInfo *domain.PlayerInfo
+	err        error
+}
+
+func (f *fakePlayerInfoRepo) Get(context.Context) (*domain.PlayerInfo, error) {
+	return f.playerInfo, f.err
+}
+
+func Test_playerInfoFallbackRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repositories")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	remote := &fakePlayerInfoRepo{}
+	local := NewPlayerInfoFileRepo(path.Join(dir, "pi.dat"), testKey)
+	repo := NewPlayerInfoFallbackRepo(remote, local, zerolog.Nop())
+	noInternetErr := &api.NoInternetError{Err: errors.New("offline")}
+
+	t.Run("offline without snapshot", func(t *testing.T) {
+		remote.playerInfo, remote.err = nil, noInternetErr
+
+		if _, err := repo.Get(ctx); err != noInternetErr {
+			t.Errorf("got %v, want %v", err, noInternetErr)
+		}
+	})
+
+	t.Run("online saves snapshot", func(t *testing.T) {
+		remote.playerInfo, remote.err = &domain.PlayerInfo{ID: 7, Name: "Venue"}, nil
+
+		if _, err := repo.Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("offline uses snapshot", func(t *testing.T) {
+		remote.playerInfo, remote.err = nil, noInternetErr
+
+		playerInfo, err := repo.Get(ctx)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if playerInfo.ID != 7 || playerInfo.Name != "Venue" {
+			t.Errorf("got %+v", playerInfo)
+		}
+	})
+
+	t.Run("unauthorized not hidden", func(t *testing.T) {
+		unauthorizedErr := &api.UnauthorizedError{Message: "revoked"}
+		remote.playerInfo, remote.err = nil, unauthorizedErr
+
+		if _, err := repo.Get(ctx); err != unauthorizedErr {
+			t.Errorf("got %v, want %v", err, unauthorizedErr)
+		}
+	})
+}
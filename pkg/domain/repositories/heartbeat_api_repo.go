@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type heartbeatApiRepo struct {
+	client api.Client
+}
+
+func NewHeartbeatApiRepo(client api.Client) *heartbeatApiRepo {
+	return &heartbeatApiRepo{
+		client: client,
+	}
+}
+
+type reqDeviceStatusTrack struct {
+	TrackID          int    `json:"trackId"`
+	Type             string `json:"type"`
+	DurationMs       int64  `json:"durationMs"`
+	PositionMs       int64  `json:"positionMs,omitempty"`
+	DownloadProgress int    `json:"downloadProgress"`
+}
+
+func (h *heartbeatApiRepo) Send(ctx context.Context, status *domain.DeviceStatus) error {
+	data := struct {
+		AppVersion    string                  `json:"appVersion"`
+		At            int64                   `json:"at"`
+		UptimeSec     int64                   `json:"uptimeSec"`
+		PlayerStatus  string                  `json:"playerStatus"`
+		Current       *reqDeviceStatusTrack   `json:"current"`
+		Playlist      []*reqDeviceStatusTrack `json:"playlist"`
+		VenueClosed   bool                    `json:"venueClosed"`
+		CachedTracks  int                     `json:"cachedTracks"`
+		TotalTracks   int                     `json:"totalTracks"`
+		CacheUsage    int64                   `json:"cacheUsage"`
+		CacheLimit    int64                   `json:"cacheLimit"`
+		FreeDisk      int64                   `json:"freeDisk"`
+		ClockOffsetMs int64                   `json:"clockOffsetMs"`
+		LastError     string                  `json:"lastError,omitempty"`
+		LastErrorAt   int64                   `json:"lastErrorAt,omitempty"`
+	}{
+		AppVersion:    status.AppVersion,
+		At:            status.At.Unix(),
+		UptimeSec:     int64(status.Uptime.Seconds()),
+		PlayerStatus:  string(status.PlayerStatus),
+		Current:       toReqDeviceStatusTrack(status.Current),
+		Playlist:      make([]*reqDeviceStatusTrack, 0, len(status.Playlist)),
+		VenueClosed:   status.VenueClosed,
+		CachedTracks:  status.Cache.CachedTracks,
+		TotalTracks:   status.Cache.TotalTracks,
+		CacheUsage:    status.Cache.UsageBytes,
+		CacheLimit:    status.Cache.LimitBytes,
+		FreeDisk:      status.FreeDiskBytes,
+		ClockOffsetMs: status.ClockOffset.Milliseconds(),
+		LastError:     status.LastError,
+	}
+
+	if !status.LastErrorAt.IsZero() {
+		data.LastErrorAt = status.LastErrorAt.Unix()
+	}
+
+	for _, t := range status.Playlist {
+		data.Playlist = append(data.Playlist, toReqDeviceStatusTrack(t))
+	}
+
+	_, err := h.client.POST(ctx, "/player/heartbeat", data)
+
+	return err
+}
+
+func toReqDeviceStatusTrack(t *domain.DeviceStatusTrack) *reqDeviceStatusTrack {
+	if t == nil {
+		return nil
+	}
+
+	return &reqDeviceStatusTrack{
+		TrackID:          t.TrackID,
+		Type:             string(t.Type),
+		DurationMs:       t.Duration.Milliseconds(),
+		PositionMs:       t.Position.Milliseconds(),
+		DownloadProgress: t.DownloadProgress.Percents(),
+	}
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/progress"
+)
+
+func Test_heartbeatApiRepo_Send(t *testing.T) {
+	var body map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/return_204":
+			w.WriteHeader(http.StatusNoContent)
+
+		case "/player/heartbeat":
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":null}`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	at := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	status := &domain.DeviceStatus{
+		AppVersion:   "1.2.3",
+		At:           at,
+		Uptime:       90 * time.Second,
+		PlayerStatus: domain.PlayerStatusPlaying,
+		Current: &domain.DeviceStatusTrack{
+			TrackID: 1, Type: domain.PlaylistTrackTypeBackground, Duration: time.Minute, Position: 30 * time.Second,
+			DownloadProgress: progress.Passed,
+		},
+		Playlist: []*domain.DeviceStatusTrack{
+			{TrackID: 2, Type: domain.PlaylistTrackTypeAd, Duration: 30 * time.Second},
+		},
+		Cache:         domain.CacheCoverage{CachedTracks: 3, TotalTracks: 4, UsageBytes: 100, LimitBytes: -1},
+		FreeDiskBytes: 1000,
+		ClockOffset:   -2 * time.Second,
+		LastError:     "no internet",
+		LastErrorAt:   at.Add(-time.Minute),
+	}
+
+	if err := NewHeartbeatApiRepo(api.NewHTTPClient(srv.URL)).Send(context.Background(), status); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"appVersion":    "1.2.3",
+		"at":            float64(at.Unix()),
+		"uptimeSec":     float64(90),
+		"playerStatus":  string(domain.PlayerStatusPlaying),
+		"venueClosed":   false,
+		"cachedTracks":  float64(3),
+		"totalTracks":   float64(4),
+		"cacheUsage":    float64(100),
+		"cacheLimit":    float64(-1),
+		"freeDisk":      float64(1000),
+		"clockOffsetMs": float64(-2000),
+		"lastError":     "no internet",
+		"lastErrorAt":   float64(at.Add(-time.Minute).Unix()),
+	}
+
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s: got %v, want %v", key, body[key], value)
+		}
+	}
+
+	current, _ := body["current"].(map[string]interface{})
+
+	if current["trackId"] != float64(1) || current["positionMs"] != float64(30000) || current["durationMs"] != float64(60000) {
+		t.Errorf("got current %v", current)
+	}
+
+	playlist, _ := body["playlist"].([]interface{})
+
+	if len(playlist) != 1 || playlist[0].(map[string]interface{})["type"] != string(domain.PlaylistTrackTypeAd) {
+		t.Errorf("got playlist %v", playlist)
+	}
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type historyFileRepo struct {
+	file encryptedFile
+}
+
+func NewHistoryFileRepo(filePath string, key string) *historyFileRepo {
+	return &historyFileRepo{
+		file: newEncryptedFile(filePath, key),
+	}
+}
+
+func (h *historyFileRepo) Set(_ context.Context, entries []*domain.HistoryEntry) error {
+	if err := h.file.write(entries); err != nil {
+		return fmt.Errorf("cannot write history: %w", err)
+	}
+
+	return nil
+}
+
+func (h *historyFileRepo) Get(_ context.Context) ([]*domain.HistoryEntry, error) {
+	var entries []*domain.HistoryEntry
+
+	if _, err := h.file.read(&entries); err != nil {
+		return nil, fmt.Errorf("cannot read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (h *historyFileRepo) Delete(_ context.Context) error {
+	if err := h.file.remove(); err != nil {
+		return fmt.Errorf("cannot remove history: %w", err)
+	}
+
+	return nil
+}
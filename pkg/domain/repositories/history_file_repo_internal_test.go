@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+func Test_historyFileRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repositories")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	repo := NewHistoryFileRepo(path.Join(dir, "h.dat"), testKey)
+
+	if entries, err := repo.Get(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("got %v, %v without file", entries, err)
+	}
+
+	playedAt := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := repo.Set(ctx, []*domain.HistoryEntry{{TrackID: 1, ArtistID: 2, PlayedAt: playedAt}}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := repo.Get(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].TrackID != 1 || entries[0].ArtistID != 2 || !entries[0].PlayedAt.Equal(playedAt) {
+		t.Errorf("got %+v", entries)
+	}
+
+	if err := repo.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := repo.Get(ctx); err != nil || len(entries) != 0 {
+		t.Errorf("got %v, %v after delete", entries, err)
+	}
+}
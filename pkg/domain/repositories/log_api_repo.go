@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/qkveri/player_core/pkg/api"
+)
+
+type logApiRepo struct {
+	client api.Client
+}
+
+func NewLogApiRepo(client api.Client) *logApiRepo {
+	return &logApiRepo{
+		client: client,
+	}
+}
+
+func (l *logApiRepo) Send(ctx context.Context, name string, content []byte) error {
+	data := struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	}{
+		Name:    name,
+		Content: string(content),
+	}
+
+	_, err := l.client.POST(ctx, "/player/logs", data)
+
+	return err
+}
@@ -46,3 +46,12 @@ func (l *loginApiRepo) Login(ctx context.Context, code string) (*domain.LoginRes
 		Token:    resLoginResponse.Token,
 	}, nil
 }
+
+// Logout отзывает текущий токен на сервере, после этого устройство не привязано к заведению.
+func (l *loginApiRepo) Logout(ctx context.Context) error {
+	if _, err := l.client.POST(ctx, "/auth/logout", struct{}{}); err != nil {
+		return err
+	}
+
+	return nil
+}
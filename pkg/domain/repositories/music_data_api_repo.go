@@ -64,12 +64,14 @@ func (m *musicDataApiRepo) Get(ctx context.Context) (*domain.MusicData, error) {
 				Days  []int `json:"days"`
 				Times []int `json:"times"`
 			} `json:"times"`
-			Track resMusicDataTrack `json:"track"`
+			Track       resMusicDataTrack `json:"track"`
+			VolumeBoost float64           `json:"volumeBoost"`
 		} `json:"ads"`
 		Intervals []struct {
-			Start    int   `json:"start"`
-			End      int   `json:"end"`
-			TrackIDs []int `json:"trackIds"`
+			Start    int      `json:"start"`
+			End      int      `json:"end"`
+			TrackIDs []int    `json:"trackIds"`
+			Volume   *float64 `json:"volume"`
 		} `json:"intervals"`
 		Tracks []resMusicDataTrack `json:"tracks"`
 	}
@@ -94,7 +96,8 @@ func (m *musicDataApiRepo) Get(ctx context.Context) (*domain.MusicData, error) {
 				Days:  ad.Times.Days,
 				Times: ad.Times.Times,
 			},
-			Track: resMusicDataTrackToTrack(ad.Track),
+			Track:       resMusicDataTrackToTrack(ad.Track),
+			VolumeBoost: ad.VolumeBoost,
 		}
 	}
 
@@ -103,6 +106,7 @@ func (m *musicDataApiRepo) Get(ctx context.Context) (*domain.MusicData, error) {
 			Start:    interval.Start,
 			End:      interval.End,
 			TrackIDs: interval.TrackIDs,
+			Volume:   interval.Volume,
 		}
 	}
 
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// musicDataFallbackRepo берет MusicData из API и сохраняет локально,
+// если API недоступно - отдает сохраненные.
+type musicDataFallbackRepo struct {
+	remote domain.MusicDataRepository
+	local  domain.MusicDataLocalRepository
+	logger zerolog.Logger
+}
+
+func NewMusicDataFallbackRepo(
+	remote domain.MusicDataRepository,
+	local domain.MusicDataLocalRepository,
+	logger zerolog.Logger,
+) *musicDataFallbackRepo {
+	return &musicDataFallbackRepo{
+		remote: remote,
+		local:  local,
+		logger: logger.With().Str("repo", "musicDataFallback").Logger(),
+	}
+}
+
+func (m *musicDataFallbackRepo) Get(ctx context.Context) (*domain.MusicData, error) {
+	musicData, err := m.remote.Get(ctx)
+
+	if err == nil {
+		if err := m.local.Set(ctx, musicData); err != nil {
+			m.logger.Warn().Err(err).Msg("musicData save to local failed")
+		}
+
+		return musicData, nil
+	}
+
+	if !canFallback(err) {
+		return nil, err
+	}
+
+	localMusicData, localErr := m.local.Get(ctx)
+
+	if localErr != nil {
+		m.logger.Warn().Err(localErr).Msg("musicData get from local failed")
+		return nil, err
+	}
+
+	if localMusicData == nil {
+		return nil, err
+	}
+
+	m.logger.Warn().Err(err).Msg("musicData remote failed, local used")
+
+	return localMusicData, nil
+}
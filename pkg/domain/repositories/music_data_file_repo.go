@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type musicDataFileRepo struct {
+	file encryptedFile
+}
+
+func NewMusicDataFileRepo(filePath string, key string) *musicDataFileRepo {
+	return &musicDataFileRepo{
+		file: newEncryptedFile(filePath, key),
+	}
+}
+
+func (m *musicDataFileRepo) Set(_ context.Context, musicData *domain.MusicData) error {
+	if err := m.file.write(musicData); err != nil {
+		return fmt.Errorf("cannot write music data: %w", err)
+	}
+
+	return nil
+}
+
+func (m *musicDataFileRepo) Get(_ context.Context) (*domain.MusicData, error) {
+	musicData := &domain.MusicData{}
+
+	exists, err := m.file.read(musicData)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read music data: %w", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	return musicData, nil
+}
+
+func (m *musicDataFileRepo) Delete(_ context.Context) error {
+	if err := m.file.remove(); err != nil {
+		return fmt.Errorf("cannot remove music data: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type playLogApiRepo struct {
+	client api.Client
+}
+
+func NewPlayLogApiRepo(client api.Client) *playLogApiRepo {
+	return &playLogApiRepo{
+		client: client,
+	}
+}
+
+type reqPlayLogEntry struct {
+	ID            string `json:"id"`
+	Event         string `json:"event"`
+	At            int64  `json:"at"`
+	TrackID       int    `json:"trackId"`
+	Type          string `json:"type"`
+	AdID          *int   `json:"adId,omitempty"`
+	AdScheduledAt *int64 `json:"adScheduledAt,omitempty"`
+	PlayedForMs   *int64 `json:"playedForMs,omitempty"`
+	Interrupted   bool   `json:"interrupted"`
+}
+
+// Send отправляет записи одним запросом. Повтор с теми же ID безопасен.
+func (p *playLogApiRepo) Send(ctx context.Context, entries []*domain.PlayLogEntry) error {
+	data := struct {
+		Entries []reqPlayLogEntry `json:"entries"`
+	}{
+		Entries: make([]reqPlayLogEntry, 0, len(entries)),
+	}
+
+	for _, e := range entries {
+		entry := reqPlayLogEntry{
+			ID:          e.ID,
+			Event:       string(e.Event),
+			At:          e.At.UnixNano() / 1e6,
+			TrackID:     e.TrackID,
+			Type:        string(e.Type),
+			Interrupted: e.Interrupted,
+		}
+
+		if e.Type == domain.PlaylistTrackTypeAd {
+			adID, scheduledAt := e.AdID, e.AdScheduledAt.Unix()
+			entry.AdID, entry.AdScheduledAt = &adID, &scheduledAt
+		}
+
+		if e.Event != domain.PlayLogEventStart {
+			playedFor := e.PlayedFor.Milliseconds()
+			entry.PlayedForMs = &playedFor
+		}
+
+		data.Entries = append(data.Entries, entry)
+	}
+
+	_, err := p.client.POST(ctx, "/player/play-log", data)
+
+	return err
+}
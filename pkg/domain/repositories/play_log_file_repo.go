@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type playLogFileRepo struct {
+	file encryptedFile
+}
+
+func NewPlayLogFileRepo(filePath string, key string) *playLogFileRepo {
+	return &playLogFileRepo{
+		file: newEncryptedFile(filePath, key),
+	}
+}
+
+func (p *playLogFileRepo) Set(_ context.Context, entries []*domain.PlayLogEntry) error {
+	if err := p.file.write(entries); err != nil {
+		return fmt.Errorf("cannot write play log: %w", err)
+	}
+
+	return nil
+}
+
+func (p *playLogFileRepo) Get(_ context.Context) ([]*domain.PlayLogEntry, error) {
+	var entries []*domain.PlayLogEntry
+
+	if _, err := p.file.read(&entries); err != nil {
+		return nil, fmt.Errorf("cannot read play log: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (p *playLogFileRepo) Delete(_ context.Context) error {
+	if err := p.file.remove(); err != nil {
+		return fmt.Errorf("cannot remove play log: %w", err)
+	}
+
+	return nil
+}
@@ -28,12 +28,18 @@ func (p *playerInfoApiRepo) Get(ctx context.Context) (*domain.PlayerInfo, error)
 	}
 
 	var resPlayerInfo struct {
-		Id           int     `json:"id"`
-		Name         string  `json:"name"`
-		HasCrossFade bool    `json:"hasCrossfade"`
-		CrossFadeSec int     `json:"crossfadeSec"`
-		ServerTime   int64   `json:"serverTime"`
-		DemoAt       *string `json:"demoAt"`
+		Id           int    `json:"id"`
+		Name         string `json:"name"`
+		HasCrossFade bool   `json:"hasCrossfade"`
+		CrossFadeSec int    `json:"crossfadeSec"`
+		ServerTime   int64  `json:"serverTime"`
+		Timezone     string `json:"timezone"`
+		OpeningHours []struct {
+			Days  []int `json:"days"`
+			Start int   `json:"start"`
+			End   int   `json:"end"`
+		} `json:"openingHours"`
+		DemoAt *string `json:"demoAt"`
 
 		Company struct {
 			Id      int    `json:"id"`
@@ -69,12 +75,20 @@ func (p *playerInfoApiRepo) Get(ctx context.Context) (*domain.PlayerInfo, error)
 		}
 	}
 
+	openingHours := make([]domain.OpeningHours, 0, len(resPlayerInfo.OpeningHours))
+
+	for _, oh := range resPlayerInfo.OpeningHours {
+		openingHours = append(openingHours, domain.OpeningHours{Days: oh.Days, Start: oh.Start, End: oh.End})
+	}
+
 	return &domain.PlayerInfo{
 		ID:                resPlayerInfo.Id,
 		Name:              resPlayerInfo.Name,
 		HasCrossFade:      resPlayerInfo.HasCrossFade,
 		CrossFadeDuration: time.Second * time.Duration(resPlayerInfo.CrossFadeSec),
 		ServerTime:        time.Unix(resPlayerInfo.ServerTime, 0),
+		Timezone:          resPlayerInfo.Timezone,
+		OpeningHours:      openingHours,
 		DemoAt:            demoAt,
 		Company: domain.PlayerInfoCompany{
 			ID:           resPlayerInfo.Company.Id,
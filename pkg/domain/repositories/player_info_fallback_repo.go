@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// playerInfoFallbackRepo берет PlayerInfo из API и сохраняет локально,
+// если API недоступно - отдает сохраненный.
+type playerInfoFallbackRepo struct {
+	remote domain.PlayerInfoRepository
+	local  domain.PlayerInfoLocalRepository
+	logger zerolog.Logger
+}
+
+func NewPlayerInfoFallbackRepo(
+	remote domain.PlayerInfoRepository,
+	local domain.PlayerInfoLocalRepository,
+	logger zerolog.Logger,
+) *playerInfoFallbackRepo {
+	return &playerInfoFallbackRepo{
+		remote: remote,
+		local:  local,
+		logger: logger.With().Str("repo", "playerInfoFallback").Logger(),
+	}
+}
+
+func (p *playerInfoFallbackRepo) Get(ctx context.Context) (*domain.PlayerInfo, error) {
+	playerInfo, err := p.remote.Get(ctx)
+
+	if err == nil {
+		if err := p.local.Set(ctx, playerInfo); err != nil {
+			p.logger.Warn().Err(err).Msg("playerInfo save to local failed")
+		}
+
+		return playerInfo, nil
+	}
+
+	if !canFallback(err) {
+		return nil, err
+	}
+
+	localPlayerInfo, localErr := p.local.Get(ctx)
+
+	if localErr != nil {
+		p.logger.Warn().Err(localErr).Msg("playerInfo get from local failed")
+		return nil, err
+	}
+
+	if localPlayerInfo == nil {
+		return nil, err
+	}
+
+	p.logger.Warn().Err(err).Msg("playerInfo remote failed, local used")
+
+	return localPlayerInfo, nil
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type playerInfoFileRepo struct {
+	file encryptedFile
+}
+
+func NewPlayerInfoFileRepo(filePath string, key string) *playerInfoFileRepo {
+	return &playerInfoFileRepo{
+		file: newEncryptedFile(filePath, key),
+	}
+}
+
+func (p *playerInfoFileRepo) Set(_ context.Context, playerInfo *domain.PlayerInfo) error {
+	if err := p.file.write(playerInfo); err != nil {
+		return fmt.Errorf("cannot write player info: %w", err)
+	}
+
+	return nil
+}
+
+func (p *playerInfoFileRepo) Get(_ context.Context) (*domain.PlayerInfo, error) {
+	playerInfo := &domain.PlayerInfo{}
+
+	exists, err := p.file.read(playerInfo)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read player info: %w", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	return playerInfo, nil
+}
+
+func (p *playerInfoFileRepo) Delete(_ context.Context) error {
+	if err := p.file.remove(); err != nil {
+		return fmt.Errorf("cannot remove player info: %w", err)
+	}
+
+	return nil
+}
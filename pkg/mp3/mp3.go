@@ -0,0 +1,186 @@
+// Package mp3 проверяет, что файл состоит из кадров MPEG Layer III, и считает его длительность.
+package mp3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	headerSize    = 4
+	id3HeaderSize = 10
+	id3FooterFlag = 0x10
+	readerSize    = 4096
+)
+
+var ErrNoFrames = errors.New("no mp3 frames found")
+
+// битрейты Layer III в кбит/с: MPEG1 и MPEG2/2.5
+var bitRates = [2][16]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// частоты дискретизации по полю версии: MPEG2.5, -, MPEG2, MPEG1
+var sampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{0, 0, 0},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+const (
+	versionMPEG1   = 3
+	versionInvalid = 1
+	layer3         = 1
+)
+
+type Info struct {
+	Frames   int
+	Duration time.Duration
+	// байты вне кадров, кроме ID3v2
+	SkippedBytes int64
+}
+
+type frame struct {
+	size     int
+	duration time.Duration
+}
+
+// parseHeader разбирает заголовок кадра Layer III. Кадры со свободным битрейтом не поддерживаются.
+func parseHeader(h []byte) (frame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return frame{}, false
+	}
+
+	version := int(h[1]>>3) & 3
+	layer := int(h[1]>>1) & 3
+	bitRateIndex := int(h[2] >> 4)
+	sampleRateIndex := int(h[2]>>2) & 3
+	padding := int(h[2]>>1) & 1
+
+	if version == versionInvalid || layer != layer3 || sampleRateIndex == 3 {
+		return frame{}, false
+	}
+
+	table, samples, coefficient := 1, 576, 72
+
+	if version == versionMPEG1 {
+		table, samples, coefficient = 0, 1152, 144
+	}
+
+	bitRate := bitRates[table][bitRateIndex] * 1000
+	sampleRate := sampleRates[version][sampleRateIndex]
+
+	if bitRate == 0 {
+		return frame{}, false
+	}
+
+	return frame{
+		size:     coefficient*bitRate/sampleRate + padding,
+		duration: time.Duration(samples) * time.Second / time.Duration(sampleRate),
+	}, true
+}
+
+// Read проходит по кадрам потока. Мусор между кадрами пропускается, а кадр, найденный
+// после потери синхронизации, засчитывается, только если за ним следует еще один заголовок.
+func Read(r io.Reader) (*Info, error) {
+	br := bufio.NewReaderSize(r, readerSize)
+
+	if err := skipID3(br); err != nil {
+		return nil, err
+	}
+
+	info := &Info{}
+	synced := false
+
+	for {
+		h, err := br.Peek(headerSize)
+
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, fmt.Errorf("cannot read: %w", err)
+		}
+
+		f, ok := parseHeader(h)
+
+		if ok && !synced {
+			next, _ := br.Peek(f.size + headerSize)
+			ok = len(next) == f.size+headerSize
+
+			if ok {
+				_, ok = parseHeader(next[f.size:])
+			}
+		}
+
+		if !ok {
+			synced = false
+			info.SkippedBytes++
+
+			if _, err := br.Discard(1); err != nil {
+				return nil, fmt.Errorf("cannot read: %w", err)
+			}
+
+			continue
+		}
+
+		// последний кадр обрезан
+		if n, _ := br.Discard(f.size); n < f.size {
+			info.SkippedBytes += int64(n)
+			break
+		}
+
+		synced = true
+		info.Frames++
+		info.Duration += f.duration
+	}
+
+	if info.Frames == 0 {
+		return nil, ErrNoFrames
+	}
+
+	return info, nil
+}
+
+func ReadFile(filePath string) (*Info, error) {
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return Read(file)
+}
+
+func skipID3(br *bufio.Reader) error {
+	h, err := br.Peek(id3HeaderSize)
+
+	if err != nil || string(h[:3]) != "ID3" {
+		return nil
+	}
+
+	// размер - syncsafe integer, по 7 бит в байте
+	size := int(h[6])<<21 | int(h[7])<<14 | int(h[8])<<7 | int(h[9])
+	size += id3HeaderSize
+
+	if h[5]&id3FooterFlag != 0 {
+		size += id3HeaderSize
+	}
+
+	if _, err := br.Discard(size); err != nil {
+		return fmt.Errorf("cannot skip id3 tag: %w", err)
+	}
+
+	return nil
+}
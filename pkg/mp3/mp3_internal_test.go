@@ -0,0 +1,73 @@
+package mp3
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// MPEG1 Layer III, 128 кбит/с, 44100 Гц, без padding - 417 байт
+var testHeader = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+const (
+	testFrameSize     = 417
+	testFrameDuration = 1152 * time.Second / 44100
+)
+
+func testFrames(n int) []byte {
+	data := make([]byte, 0, n*testFrameSize)
+
+	for i := 0; i < n; i++ {
+		data = append(data, testHeader...)
+		data = append(data, make([]byte, testFrameSize-len(testHeader))...)
+	}
+
+	return data
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func Test_Read(t *testing.T) {
+	id3 := join([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 5}, []byte{0xFF, 0xFB, 0x90, 0, 0})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		frames  int
+		skipped int64
+		err     error
+	}{
+		{name: "frames", data: testFrames(100), frames: 100},
+		{name: "id3 tag skipped", data: join(id3, testFrames(10)), frames: 10},
+		{name: "garbage before frames", data: join([]byte("junk\xFF\xFB"), testFrames(10)), frames: 10, skipped: 6},
+		{name: "truncated last frame", data: testFrames(10)[:9*testFrameSize+100], frames: 9, skipped: 100},
+		{name: "single false sync", data: join([]byte("<html>"), testHeader, []byte("</html>")), err: ErrNoFrames},
+		{name: "html", data: []byte("<html><body>502 Bad Gateway</body></html>"), err: ErrNoFrames},
+		{name: "empty", err: ErrNoFrames},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Read(bytes.NewReader(tt.data))
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if info.Frames != tt.frames || info.SkippedBytes != tt.skipped {
+				t.Errorf("got %d frames, %d skipped, want %d, %d", info.Frames, info.SkippedBytes, tt.frames, tt.skipped)
+			}
+
+			if want := time.Duration(tt.frames) * testFrameDuration; info.Duration != want {
+				t.Errorf("got duration %s, want %s", info.Duration, want)
+			}
+		})
+	}
+}
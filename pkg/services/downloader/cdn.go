@@ -0,0 +1,157 @@
+package downloader
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cavaliercoder/grab"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const (
+	// упавшее зеркало пропускается на это время
+	mirrorCoolDown = 5 * time.Minute
+
+	dialTimeout           = 10 * time.Second
+	responseHeaderTimeout = 15 * time.Second
+	// скачивание без новых данных столько времени считается зависшим
+	stallTimeout = time.Minute
+)
+
+// source - откуда качать трек: база CDN (пустая - URL трека как есть) и полный URL.
+type source struct {
+	base string
+	url  string
+}
+
+// mirrors - запасные CDN и их здоровье.
+type mirrors struct {
+	mu        sync.Mutex
+	fallbacks []string
+	deadUntil map[string]time.Time
+
+	logger zerolog.Logger
+}
+
+func newMirrors(fallbacks []string, logger zerolog.Logger) *mirrors {
+	return &mirrors{
+		fallbacks: fallbacks,
+		deadUntil: make(map[string]time.Time),
+		logger:    logger,
+	}
+}
+
+func (m *mirrors) SetFallbacks(fallbacks []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fallbacks = fallbacks
+}
+
+// sources - URL трека на основном CDN и зеркалах. Относительный MP3URL считается от базы CDN,
+// абсолютный с основного CDN переносится на зеркала, другой абсолютный качается как есть.
+// Живые источники идут первыми, упавшие - в конце, на крайний случай.
+func (m *mirrors) sources(cdnURL string, track *domain.Track) []source {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rel := track.MP3URL
+
+	if u, err := url.Parse(track.MP3URL); err == nil && u.IsAbs() {
+		if cdnURL == "" || !strings.HasPrefix(track.MP3URL, withSlash(cdnURL)) {
+			return []source{{url: track.MP3URL}}
+		}
+
+		rel = strings.TrimPrefix(track.MP3URL, withSlash(cdnURL))
+	}
+
+	rel = strings.TrimPrefix(rel, "/")
+
+	var alive, dead []source
+
+	for _, base := range append([]string{cdnURL}, m.fallbacks...) {
+		if base == "" {
+			continue
+		}
+
+		resolved, err := resolveURL(base, rel)
+
+		if err != nil {
+			m.logger.Warn().Err(err).Str("base", base).Str("mp3Url", track.MP3URL).Msg("cannot resolve track url")
+			continue
+		}
+
+		if time.Now().Before(m.deadUntil[base]) {
+			dead = append(dead, source{base: base, url: resolved})
+		} else {
+			alive = append(alive, source{base: base, url: resolved})
+		}
+	}
+
+	return append(alive, dead...)
+}
+
+func (m *mirrors) markFailed(src source, err error) {
+	if src.base == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deadUntil[src.base] = time.Now().Add(mirrorCoolDown)
+
+	m.logger.Warn().Err(err).Str("base", src.base).Dur("coolDown", mirrorCoolDown).Msg("mirror marked dead")
+}
+
+func (m *mirrors) markAlive(src source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.deadUntil, src.base)
+}
+
+func withSlash(base string) string {
+	if strings.HasSuffix(base, "/") {
+		return base
+	}
+
+	return base + "/"
+}
+
+func resolveURL(base, rel string) (string, error) {
+	b, err := url.Parse(withSlash(base))
+
+	if err != nil {
+		return "", err
+	}
+
+	r, err := url.Parse(rel)
+
+	if err != nil {
+		return "", err
+	}
+
+	return b.ResolveReference(r).String(), nil
+}
+
+// newGrabClient - клиент с таймаутами: без них недоступный CDN вешает скачивание навсегда.
+func newGrabClient() *grab.Client {
+	client := grab.NewClient()
+	client.HTTPClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			TLSHandshakeTimeout:   dialTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+		},
+	}
+
+	return client
+}
@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func sourceURLs(sources []source) []string {
+	urls := make([]string, 0, len(sources))
+
+	for _, src := range sources {
+		urls = append(urls, src.url)
+	}
+
+	return urls
+}
+
+func Test_mirrors_sources(t *testing.T) {
+	m := newMirrors([]string{"https://m1.example.com/music", "https://m2.example.com/"}, zerolog.Nop())
+
+	tests := []struct {
+		name   string
+		cdnURL string
+		mp3URL string
+		want   []string
+	}{
+		{
+			name:   "relative",
+			cdnURL: "https://cdn.example.com/music/",
+			mp3URL: "tracks/1.mp3",
+			want: []string{
+				"https://cdn.example.com/music/tracks/1.mp3",
+				"https://m1.example.com/music/tracks/1.mp3",
+				"https://m2.example.com/tracks/1.mp3",
+			},
+		},
+		{
+			name:   "absolute on cdn",
+			cdnURL: "https://cdn.example.com/music",
+			mp3URL: "https://cdn.example.com/music/tracks/1.mp3",
+			want: []string{
+				"https://cdn.example.com/music/tracks/1.mp3",
+				"https://m1.example.com/music/tracks/1.mp3",
+				"https://m2.example.com/tracks/1.mp3",
+			},
+		},
+		{
+			name:   "absolute elsewhere",
+			cdnURL: "https://cdn.example.com/music",
+			mp3URL: "https://other.example.com/1.mp3",
+			want:   []string{"https://other.example.com/1.mp3"},
+		},
+		{
+			name:   "relative without cdn",
+			mp3URL: "/tracks/1.mp3",
+			want: []string{
+				"https://m1.example.com/music/tracks/1.mp3",
+				"https://m2.example.com/tracks/1.mp3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sourceURLs(m.sources(tt.cdnURL, &domain.Track{ID: 1, MP3URL: tt.mp3URL}))
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("dead mirror last until alive", func(t *testing.T) {
+		track := &domain.Track{ID: 1, MP3URL: "1.mp3"}
+		sources := m.sources("https://cdn.example.com/", track)
+
+		m.markFailed(sources[0], errors.New("timeout"))
+
+		want := []string{"https://m1.example.com/music/1.mp3", "https://m2.example.com/1.mp3", "https://cdn.example.com/1.mp3"}
+
+		if got := sourceURLs(m.sources("https://cdn.example.com/", track)); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		m.markAlive(sources[0])
+
+		if got := m.sources("https://cdn.example.com/", track)[0]; got != sources[0] {
+			t.Errorf("got %v first, want %v", got, sources[0])
+		}
+	})
+}
+
+func Test_NewService_mirrors(t *testing.T) {
+	svc := NewService(state.NewState(), zerolog.Nop(), clockwork.NewRealClock(), func(error) {}, func(domain.CacheCoverage) {}, nil, "",
+		Config{CdnMirrors: []string{"https://m1.example.com/"}})
+	track := &domain.Track{ID: 1, MP3URL: "1.mp3"}
+
+	want := []string{"https://cdn.example.com/1.mp3", "https://m1.example.com/1.mp3"}
+
+	if got := sourceURLs(svc.mirrors.sources("https://cdn.example.com/", track)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	svc.SetCdnMirrors([]string{"https://m2.example.com/"})
+
+	want = []string{"https://cdn.example.com/1.mp3", "https://m2.example.com/1.mp3"}
+
+	if got := sourceURLs(svc.mirrors.sources("https://cdn.example.com/", track)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after SetCdnMirrors, want %v", got, want)
+	}
+
+	if svc.client == nil {
+		t.Errorf("grab client not set")
+	}
+}
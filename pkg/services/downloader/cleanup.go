@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// недокачанные файлы старше partialTTL не докачиваем
+const partialTTL = 72 * time.Hour
+
+// cleanupPartials удаляет брошенные недокачанные файлы: пустые и устаревшие.
+// Остальные остаются и докачиваются, когда трек снова понадобится.
+func (s *service) cleanupPartials() {
+	files, err := ioutil.ReadDir(s.mp3RootDir)
+
+	if err != nil {
+		s.logger.Err(err).Str("mp3RootDir", s.mp3RootDir).Msg("cleanup partials: read dir fail")
+		return
+	}
+
+	kept := 0
+
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), partialExt) {
+			continue
+		}
+
+		if fi.Size() > 0 && time.Since(fi.ModTime()) < partialTTL {
+			kept++
+			continue
+		}
+
+		filePath := path.Join(s.mp3RootDir, fi.Name())
+
+		if err := os.Remove(filePath); err != nil {
+			s.logger.Err(err).Str("filePath", filePath).Msg("cleanup partials: remove fail")
+			continue
+		}
+
+		s.logger.Debug().Str("filePath", filePath).
+			Int64("size", fi.Size()).
+			Time("modTime", fi.ModTime()).
+			Msg("orphaned partial removed")
+	}
+
+	s.logger.Debug().Int("kept", kept).Msg("cleanup partials done")
+}
+
+// cleanupQuarantine удаляет испорченные файлы старше quarantineTTL.
+func (s *service) cleanupQuarantine() {
+	dir := path.Join(s.mp3RootDir, quarantineDirName)
+	files, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Err(err).Str("dir", dir).Msg("cleanup quarantine: read dir fail")
+		}
+
+		return
+	}
+
+	for _, fi := range files {
+		if time.Since(fi.ModTime()) < quarantineTTL {
+			continue
+		}
+
+		filePath := path.Join(dir, fi.Name())
+
+		if err := os.Remove(filePath); err != nil {
+			s.logger.Err(err).Str("filePath", filePath).Msg("cleanup quarantine: remove fail")
+		}
+	}
+}
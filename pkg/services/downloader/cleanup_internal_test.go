@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func Test_cleanupPartials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]struct {
+		data string
+		age  time.Duration
+		kept bool
+	}{
+		"1.part": {data: "mp3", kept: true},
+		"2.part": {data: "", kept: false},
+		"3.part": {data: "mp3", age: partialTTL + time.Hour, kept: false},
+		// полные файлы не трогаем, даже старые
+		"4": {data: "mp3", age: partialTTL + time.Hour, kept: true},
+	}
+
+	for name, f := range files {
+		filePath := path.Join(dir, name)
+
+		if err := ioutil.WriteFile(filePath, []byte(f.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		modTime := time.Now().Add(-f.age)
+
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := &service{mp3RootDir: dir, logger: zerolog.Nop()}
+	svc.cleanupPartials()
+
+	for name, f := range files {
+		_, err := os.Stat(path.Join(dir, name))
+
+		if kept := !os.IsNotExist(err); kept != f.kept {
+			t.Errorf("%s: got kept %v, want %v", name, kept, f.kept)
+		}
+	}
+}
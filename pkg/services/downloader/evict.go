@@ -0,0 +1,212 @@
+package downloader
+
+import (
+	"context"
+	"sort"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// предзагрузка останавливается на этой доле лимита, чтобы не вытеснять только что скачанное
+const prefetchFillRatio = 0.9
+
+// maintainCache удаляет файлы треков, которых больше нет в музыкальных настройках,
+// и вытесняет давно не игравшие треки, пока кеш не уложится в лимит.
+// Не трогает треки плейлиста, текущего трека, их интервалов и рекламу.
+func (s *service) maintainCache() {
+	protected := s.protectedTrackIDs()
+
+	if s.musicData != nil && s.musicData != s.purgedMusicData {
+		s.purge(protected)
+		s.purgedMusicData = s.musicData
+	}
+
+	if s.config.CacheMaxSize >= 0 {
+		s.evict(protected)
+	}
+}
+
+func (s *service) purge(protected map[int]struct{}) {
+	library := make(map[int]struct{})
+
+	for _, t := range s.libraryTracks() {
+		library[t.ID] = struct{}{}
+	}
+
+	for _, e := range s.index.Entries() {
+		if _, ok := library[e.TrackID]; ok {
+			continue
+		}
+
+		if _, ok := protected[e.TrackID]; ok {
+			continue
+		}
+
+		if err := s.index.Remove(e.TrackID); err != nil {
+			s.logger.Err(err).Int("trackId", e.TrackID).Msg("purge fail")
+			continue
+		}
+
+		s.logger.Debug().Int("trackId", e.TrackID).Msg("track not in musicData, purged")
+	}
+}
+
+func (s *service) evict(protected map[int]struct{}) {
+	usage := s.index.Usage()
+
+	if usage <= s.config.CacheMaxSize {
+		return
+	}
+
+	entries := s.index.Entries()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsedAt().Before(entries[j].LastUsedAt())
+	})
+
+	for _, e := range entries {
+		if usage <= s.config.CacheMaxSize {
+			break
+		}
+
+		if _, ok := protected[e.TrackID]; ok {
+			continue
+		}
+
+		if err := s.index.Remove(e.TrackID); err != nil {
+			s.logger.Err(err).Int("trackId", e.TrackID).Msg("evict fail")
+			continue
+		}
+
+		usage -= e.Size
+
+		s.logger.Debug().Int("trackId", e.TrackID).
+			Time("lastUsedAt", e.LastUsedAt()).
+			Int64("usage", usage).
+			Msg("track evicted")
+	}
+
+	if usage > s.config.CacheMaxSize {
+		s.logger.Warn().Int64("usage", usage).
+			Int64("limit", s.config.CacheMaxSize).
+			Msg("cache over limit, only protected tracks left")
+	}
+}
+
+func (s *service) protectedTrackIDs() map[int]struct{} {
+	protected := make(map[int]struct{})
+	intervals := make(map[int]struct{})
+
+	s.state.Player.RLock()
+	current := s.state.Player.Current()
+	s.state.Player.RUnlock()
+
+	s.state.Playlist.RLock()
+	items := append(s.state.Playlist.Get()[:0:0], s.state.Playlist.Get()...)
+	s.state.Playlist.RUnlock()
+
+	if current != nil {
+		items = append(items, current)
+	}
+
+	for _, pt := range items {
+		protected[pt.Track.ID] = struct{}{}
+
+		if pt.Type == domain.PlaylistTrackTypeBackground {
+			intervals[pt.BackgroundIntervalIndex] = struct{}{}
+		}
+	}
+
+	if s.musicData == nil {
+		return protected
+	}
+
+	for index := range intervals {
+		if index < len(s.musicData.Intervals) {
+			for _, id := range s.musicData.Intervals[index].TrackIDs {
+				protected[id] = struct{}{}
+			}
+		}
+	}
+
+	for _, ad := range s.musicData.Ads {
+		if ad.Track != nil {
+			protected[ad.Track.ID] = struct{}{}
+		}
+	}
+
+	return protected
+}
+
+// ClearCache удаляет все скачанные треки, кроме текущего и плейлиста, и возвращает, сколько удалено.
+// Предзагрузка, если включена, начнется заново.
+func (s *service) ClearCache(ctx context.Context) (int, error) {
+	done := make(chan int, 1)
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case s.clearRequests <- done:
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case removed := <-done:
+		return removed, nil
+	}
+}
+
+func (s *service) clearCache() int {
+	// предзагрузка скачала бы удаленное заново
+	s.jm.Lock()
+	for trackID, j := range s.jobs {
+		if j.priority == priorityPrefetch && !j.canceled {
+			s.logger.Debug().Int("trackId", trackID).Msg("job canceled (cache cleared)")
+			j.cancel()
+		}
+	}
+	s.jm.Unlock()
+
+	playing := make(map[int]struct{})
+
+	s.state.Player.RLock()
+	if current := s.state.Player.Current(); current != nil {
+		playing[current.Track.ID] = struct{}{}
+	}
+	s.state.Player.RUnlock()
+
+	s.state.Playlist.RLock()
+	for _, pt := range s.state.Playlist.Get() {
+		playing[pt.Track.ID] = struct{}{}
+	}
+	s.state.Playlist.RUnlock()
+
+	removed := 0
+
+	for _, e := range s.index.Entries() {
+		if _, ok := playing[e.TrackID]; ok {
+			continue
+		}
+
+		if err := s.index.Remove(e.TrackID); err != nil {
+			s.logger.Err(err).Int("trackId", e.TrackID).Msg("clear cache fail")
+			continue
+		}
+
+		removed++
+	}
+
+	s.logger.Info().Int("removed", removed).Int64("usage", s.index.Usage()).Msg("cache cleared")
+
+	return removed
+}
+
+// cacheFull - кеш заполнен настолько, что предзагрузку надо остановить.
+func (s *service) cacheFull() bool {
+	if s.config.CacheMaxSize < 0 {
+		return false
+	}
+
+	return float64(s.index.Usage()) >= float64(s.config.CacheMaxSize)*prefetchFillRatio
+}
@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/cache"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func Test_maintainCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	index := cache.NewFileIndex(dir, zerolog.Nop())
+	playedAt := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tracks := make([]*domain.Track, 0, 5)
+
+	for id := 1; id <= 5; id++ {
+		tracks = append(tracks, &domain.Track{ID: id})
+
+		if err := ioutil.WriteFile(index.FilePath(id), []byte("0123456789"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := index.Put(&domain.CacheEntry{TrackID: id, FileName: index.FilePath(id)[len(dir)+1:], Size: 10}); err != nil {
+			t.Fatal(err)
+		}
+
+		// 1 - самый давний
+		if err := index.Touch(id, playedAt.Add(time.Duration(id)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := state.NewState()
+	st.Playlist.Append(&domain.PlaylistTrack{Track: tracks[0], Type: domain.PlaylistTrackTypeBackground})
+
+	svc := NewService(st, zerolog.Nop(), clockwork.NewRealClock(), func(error) {}, func(domain.CacheCoverage) {}, index, dir,
+		Config{Concurrency: 1, CacheMaxSize: 25})
+
+	// трека 5 больше нет в музыкальных настройках
+	svc.musicData = &domain.MusicData{
+		Intervals: []*domain.MusicDataInterval{{TrackIDs: []int{1, 2, 3, 4}}},
+		Tracks:    tracks[:4],
+	}
+
+	svc.maintainCache()
+
+	if _, ok := index.Entry(5); ok {
+		t.Errorf("track 5 not purged")
+	}
+
+	// трек 1 в плейлисте, а его интервал защищает 2..4 - вытеснять нечего
+	if got := index.Usage(); got != 40 {
+		t.Errorf("got usage %d, want 40", got)
+	}
+
+	svc.musicData.Intervals = []*domain.MusicDataInterval{{TrackIDs: []int{1}}, {TrackIDs: []int{2, 3, 4}}}
+	svc.maintainCache()
+
+	// 2 и 3 - самые давние незащищенные
+	for id, want := range map[int]bool{1: true, 2: false, 3: false, 4: true} {
+		if _, ok := index.Entry(id); ok != want {
+			t.Errorf("track %d: got cached %v, want %v", id, ok, want)
+		}
+	}
+
+	// очистка оставляет только трек плейлиста
+	if removed := svc.clearCache(); removed != 1 {
+		t.Errorf("got removed %d, want 1", removed)
+	}
+
+	if _, ok := index.Entry(1); !ok || index.Usage() != 10 {
+		t.Errorf("got usage %d, want only track 1 cached", index.Usage())
+	}
+}
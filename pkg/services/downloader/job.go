@@ -0,0 +1,205 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cavaliercoder/grab"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/progress"
+)
+
+const (
+	durationProgressPoll = 200 * time.Millisecond
+	partialExt           = ".part"
+)
+
+// приоритеты скачивания, меньше - важнее
+const (
+	priorityNext = iota
+	priorityAd
+	priorityLookahead
+	priorityPrefetch
+)
+
+// job - скачивание одного трека.
+type job struct {
+	ctxCancel context.CancelFunc
+	canceled  bool
+
+	track    *domain.Track
+	priority int
+	// элементы плейлиста, ожидающие этот трек
+	playlistTracks []*domain.PlaylistTrack
+	filePath       string
+	// основной CDN и зеркала по порядку
+	sources []source
+	// источник, с которого трек скачан
+	servedBy source
+
+	client  *grab.Client
+	limiter *rateLimiter
+	mirrors *mirrors
+}
+
+func (j *job) cancel() {
+	j.canceled = true
+	j.ctxCancel()
+}
+
+// download возвращает запись для индекса кеша. Источники перебираются по порядку,
+// пока один из них не отдаст трек, упавшие помечаются в j.mirrors.
+func (j *job) download(ctx context.Context, progressCh chan<- progress.Progress) (*domain.CacheEntry, error) {
+	defer j.ctxCancel()
+
+	if len(j.sources) == 0 {
+		//nolint:goerr113
+		return nil, fmt.Errorf("no source url, mp3URL: %s", j.track.MP3URL)
+	}
+
+	var err error
+
+	for _, src := range j.sources {
+		var entry *domain.CacheEntry
+
+		entry, err = j.downloadFrom(ctx, src, progressCh)
+
+		if err == nil {
+			j.servedBy = src
+			j.mirrors.markAlive(src)
+
+			return entry, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// зеркала - копии основного CDN, испорченный трек испорчен везде.
+		// Но HTML вместо mp3 - ошибка самого CDN
+		var corruptedErr *CorruptedFileError
+
+		if errors.As(err, &corruptedErr) && !errors.Is(err, errUnexpectedContentType) {
+			return nil, err
+		}
+
+		j.mirrors.markFailed(src, err)
+	}
+
+	return nil, err
+}
+
+// downloadFrom качает трек с одного источника.
+// Трек качается в partialExt-файл и переименовывается только после успешного завершения,
+// поэтому файл без расширения всегда полный. Прерванное скачивание grab докачает
+// Range-запросом при следующей попытке, в том числе с другого зеркала.
+func (j *job) downloadFrom(ctx context.Context, src source, progressCh chan<- progress.Progress) (*domain.CacheEntry, error) {
+	filePath := j.filePath
+	partialPath := filePath + partialExt
+
+	req, err := grab.NewRequest(partialPath, src.url)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot grab.NewRequest: %w, filePath: %s, url: %s",
+			err, partialPath, src.url)
+	}
+
+	// время изменения - локальное, по нему чистятся устаревшие куски
+	req.IgnoreRemoteTime = true
+	req.RateLimiter = j.limiter
+	req.BeforeCopy = func(resp *grab.Response) error {
+		if err := checkContentType(resp); err != nil {
+			return &CorruptedFileError{TrackID: j.track.ID, Err: err}
+		}
+
+		return nil
+	}
+	req = req.WithContext(ctx)
+
+	resp := j.client.Do(req)
+
+	t := time.NewTicker(durationProgressPoll)
+	defer t.Stop()
+
+	lastBytes, lastProgressAt := int64(0), time.Now()
+
+Loop:
+	for {
+		select {
+		case <-t.C:
+			if bytes := resp.BytesComplete(); bytes != lastBytes {
+				lastBytes, lastProgressAt = bytes, time.Now()
+			} else if time.Since(lastProgressAt) > stallTimeout {
+				_ = resp.Cancel()
+				<-resp.Done
+
+				//nolint:goerr113
+				return nil, fmt.Errorf("download stalled: no data for %s, url: %s", stallTimeout, src.url)
+			}
+
+			// dispatch to progress chan...
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+
+			case progressCh <- progress.Progress(resp.Progress()):
+				break
+			}
+		case <-resp.Done:
+			break Loop
+		}
+	}
+
+	if err := resp.Err(); err != nil {
+		var corruptedErr *CorruptedFileError
+
+		switch {
+		// локальный кусок больше файла на сервере - докачать нельзя
+		case errors.Is(err, grab.ErrBadLength):
+			_ = os.Remove(partialPath)
+
+		case errors.As(err, &corruptedErr):
+			j.quarantine(partialPath)
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("download failed: %w, url: %s, resumed: %v", err, src.url, resp.DidResume)
+	}
+
+	size, err := verifyFile(partialPath, resp.Size, j.track)
+
+	if err != nil {
+		j.quarantine(partialPath)
+		return nil, &CorruptedFileError{TrackID: j.track.ID, Err: err}
+	}
+
+	if err := os.Rename(partialPath, filePath); err != nil {
+		return nil, fmt.Errorf("cannot os.Rename: %w, from: %s, to: %s", err, partialPath, filePath)
+	}
+
+	entry := &domain.CacheEntry{
+		TrackID:      j.track.ID,
+		FileName:     path.Base(filePath),
+		URL:          j.track.MP3URL,
+		Size:         size,
+		DownloadedAt: time.Now(),
+	}
+
+	if resp.HTTPResponse != nil {
+		entry.ETag = resp.HTTPResponse.Header.Get("ETag")
+	}
+
+	return entry, nil
+}
+
+// quarantine убирает испорченный кусок, чтобы следующая попытка не докачивала его.
+func (j *job) quarantine(partialPath string) {
+	if err := quarantine(partialPath, j.track.ID); err != nil {
+		_ = os.Remove(partialPath)
+	}
+}
@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/progress"
+)
+
+func Test_job_download_resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	data := testMP3(383)
+	half := len(data) / 2
+
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+		}
+
+		mp3Handler(data).ServeHTTP(w, r)
+	}))
+
+	defer server.Close()
+
+	track := &domain.Track{ID: 1, MP3URL: "1.mp3", Duration: 10 * time.Second}
+	filePath := path.Join(dir, "1")
+
+	// первая половина скачана прошлой попыткой
+	if err := ioutil.WriteFile(filePath+partialExt, data[:half], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mirrors := newMirrors(nil, zerolog.Nop())
+	j := &job{
+		ctxCancel: func() {},
+		track:     track,
+		filePath:  filePath,
+		sources:   mirrors.sources(server.URL, track),
+		client:    newGrabClient(),
+		limiter:   newRateLimiter(0),
+		mirrors:   mirrors,
+	}
+
+	progressCh := make(chan progress.Progress)
+	defer close(progressCh)
+
+	go func() {
+		for range progressCh {
+		}
+	}()
+
+	entry, err := j.download(context.Background(), progressCh)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	if want := []string{"bytes=" + strconv.Itoa(half) + "-"}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got ranges %q, want %q", ranges, want)
+	}
+	mu.Unlock()
+
+	if entry.Size != int64(len(data)) || entry.FileName != "1" {
+		t.Errorf("got entry %+v", entry)
+	}
+
+	// полный файл переименован, кусок не остался
+	if got, err := ioutil.ReadFile(filePath); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("got file of %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+
+	if _, err := os.Stat(filePath + partialExt); !os.IsNotExist(err) {
+		t.Errorf("partial file left: %v", err)
+	}
+}
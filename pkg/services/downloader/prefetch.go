@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// libraryTracks - все треки библиотеки: сначала реклама, затем музыка.
+func (s *service) libraryTracks() []*domain.Track {
+	if s.musicData == nil {
+		return nil
+	}
+
+	tracks := make([]*domain.Track, 0, len(s.musicData.Ads)+len(s.musicData.Tracks))
+	seen := make(map[int]struct{}, cap(tracks))
+
+	add := func(t *domain.Track) {
+		if t == nil {
+			return
+		}
+
+		if _, ok := seen[t.ID]; ok {
+			return
+		}
+
+		seen[t.ID] = struct{}{}
+		tracks = append(tracks, t)
+	}
+
+	for _, ad := range s.musicData.Ads {
+		add(ad.Track)
+	}
+
+	for _, t := range s.musicData.Tracks {
+		add(t)
+	}
+
+	return tracks
+}
+
+// prefetchWanted - нескачанные треки библиотеки с низшим приоритетом,
+// не больше числа воркеров. Вызывать под s.jm.
+func (s *service) prefetchWanted() []*wanted {
+	var list []*wanted
+
+	if s.cacheFull() {
+		s.logger.Debug().Msg("prefetch skipped (cache full)")
+		return nil
+	}
+
+	for _, t := range s.libraryTracks() {
+		if len(list) >= s.config.Concurrency {
+			break
+		}
+
+		if _, ok := s.index.Entry(t.ID); ok {
+			continue
+		}
+
+		if s.retryPending(t.ID) {
+			continue
+		}
+
+		list = append(list, &wanted{track: t, priority: priorityPrefetch})
+	}
+
+	return list
+}
+
+// reportCoverage сообщает покрытие библиотеки кешем, если оно изменилось.
+func (s *service) reportCoverage() {
+	tracks := s.libraryTracks()
+	coverage := domain.CacheCoverage{
+		TotalTracks: len(tracks),
+		UsageBytes:  s.index.Usage(),
+		LimitBytes:  s.config.CacheMaxSize,
+	}
+
+	for _, t := range tracks {
+		if e, ok := s.index.Entry(t.ID); ok {
+			coverage.CachedTracks++
+			coverage.CachedBytes += e.Size
+		}
+	}
+
+	if coverage == s.coverage {
+		return
+	}
+
+	s.coverage = coverage
+
+	s.logger.Debug().Interface("coverage", coverage).Msg("cache coverage changed")
+
+	s.state.Cache.Lock()
+	s.state.Cache.SetCoverage(coverage)
+	s.state.Cache.Unlock()
+
+	s.coverageCb(coverage)
+}
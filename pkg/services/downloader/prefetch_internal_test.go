@@ -0,0 +1,112 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/cache"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func Test_prefetch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	index := cache.NewFileIndex(dir, zerolog.Nop())
+
+	var reported []domain.CacheCoverage
+
+	st := state.NewState()
+	svc := NewService(st, zerolog.Nop(), clockwork.NewRealClock(), func(error) {},
+		func(coverage domain.CacheCoverage) { reported = append(reported, coverage) },
+		index, dir, Config{Prefetch: true, Concurrency: 3, CacheMaxSize: -1})
+
+	tracks := []*domain.Track{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	svc.musicData = &domain.MusicData{
+		// реклама 5 - раньше музыки, трек 1 и в рекламе, и в музыке
+		Ads:    []*domain.Ad{{ID: 1, Track: &domain.Track{ID: 5}}, {ID: 2, Track: tracks[0]}},
+		Tracks: tracks,
+	}
+
+	if err := ioutil.WriteFile(index.FilePath(2), []byte("mp3 data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := index.Put(&domain.CacheEntry{TrackID: 2, FileName: "2", Size: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantedIDs := func() []int {
+		var ids []int
+
+		for _, w := range svc.prefetchWanted() {
+			if w.priority != priorityPrefetch {
+				t.Errorf("track %d: got priority %d, want prefetch", w.track.ID, w.priority)
+			}
+
+			ids = append(ids, w.track.ID)
+		}
+
+		return ids
+	}
+
+	t.Run("ads first, cached skipped, up to concurrency", func(t *testing.T) {
+		if got, want := wantedIDs(), []int{5, 1, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("failed track waits for retry", func(t *testing.T) {
+		svc.markFailed(5)
+		defer svc.markSucceeded(5)
+
+		if got, want := wantedIDs(), []int{1, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("full cache", func(t *testing.T) {
+		svc.config.CacheMaxSize = 8
+		defer func() { svc.config.CacheMaxSize = -1 }()
+
+		if got := wantedIDs(); len(got) != 0 {
+			t.Errorf("got %v, want none", got)
+		}
+	})
+
+	t.Run("coverage reported on change only", func(t *testing.T) {
+		svc.reportCoverage()
+		svc.reportCoverage()
+
+		want := domain.CacheCoverage{CachedTracks: 1, TotalTracks: 5, CachedBytes: 8, UsageBytes: 8, LimitBytes: -1}
+
+		if len(reported) != 1 || reported[0] != want {
+			t.Fatalf("got reported %+v, want [%+v]", reported, want)
+		}
+
+		if got := st.Cache.Coverage(); got != want {
+			t.Errorf("got state coverage %+v, want %+v", got, want)
+		}
+
+		if err := index.Remove(2); err != nil {
+			t.Fatal(err)
+		}
+
+		svc.reportCoverage()
+
+		if len(reported) != 2 || reported[1].CachedTracks != 0 {
+			t.Errorf("got reported %+v after remove", reported)
+		}
+	})
+}
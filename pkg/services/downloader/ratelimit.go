@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter - общий для всех скачиваний token bucket (grab.RateLimiter).
+// Запас не больше секунды трафика, долг отрабатывается ожиданием.
+type rateLimiter struct {
+	mu sync.Mutex
+	// байт в секунду, <= 0 - без лимита
+	limit  int64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit int64) *rateLimiter {
+	return &rateLimiter{limit: limit}
+}
+
+func (l *rateLimiter) SetLimit(limit int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.tokens = 0
+	l.last = time.Time{}
+}
+
+func (l *rateLimiter) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+func (l *rateLimiter) WaitN(ctx context.Context, n int) error {
+	wait := l.reserve(n)
+
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-t.C:
+		return nil
+	}
+}
+
+// reserve списывает n байт и возвращает, сколько нужно подождать.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.limit)
+
+		if l.tokens > float64(l.limit) {
+			l.tokens = float64(l.limit)
+		}
+	}
+
+	l.last = now
+	l.tokens -= float64(n)
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / float64(l.limit) * float64(time.Second))
+}
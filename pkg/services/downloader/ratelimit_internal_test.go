@@ -0,0 +1,39 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_rateLimiter(t *testing.T) {
+	t.Run("no limit", func(t *testing.T) {
+		l := newRateLimiter(0)
+
+		if wait := l.reserve(1 << 20); wait != 0 {
+			t.Errorf("got wait %s, want 0", wait)
+		}
+	})
+
+	t.Run("debt grows", func(t *testing.T) {
+		l := newRateLimiter(1000)
+
+		if wait := l.reserve(500); wait < 490*time.Millisecond || wait > 500*time.Millisecond {
+			t.Errorf("got wait %s, want ~500ms", wait)
+		}
+
+		if wait := l.reserve(500); wait < 990*time.Millisecond || wait > time.Second {
+			t.Errorf("got wait %s, want ~1s", wait)
+		}
+	})
+
+	t.Run("canceled wait", func(t *testing.T) {
+		l := newRateLimiter(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := l.WaitN(ctx, 100); err != context.Canceled {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	})
+}
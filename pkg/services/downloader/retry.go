@@ -0,0 +1,139 @@
+package downloader
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/utils"
+)
+
+const (
+	retryMin = 10 * time.Second
+	retryMax = 30 * time.Minute
+	// после стольких неудач подряд трек считается недоступным
+	maxTrackFailures = 5
+	// недоступный трек пробуем снова не раньше, чем через unavailableRetry
+	unavailableRetry = time.Hour
+)
+
+type LookaheadFailingError struct {
+	Tracks int
+	Err    error
+}
+
+func (e *LookaheadFailingError) Error() string {
+	return fmt.Sprintf("cannot download any playlist track: {tracks: %d, lastError: %v}", e.Tracks, e.Err)
+}
+
+func (e *LookaheadFailingError) Unwrap() error {
+	return e.Err
+}
+
+// failure - неудачные скачивания трека подряд.
+type failure struct {
+	attempts int
+	retryAt  time.Time
+}
+
+// retryDelay - экспоненциальная задержка перед попыткой attempts+1 со случайным
+// разбросом в пределах [d/2, d), чтобы треки, упавшие вместе, не повторялись разом.
+func retryDelay(attempts int) time.Duration {
+	d := utils.Backoff(attempts, retryMin, retryMax)
+
+	//nolint:gosec
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// markFailed откладывает следующую попытку скачать трек. После maxTrackFailures
+// неудач трек помечается недоступным, и плейлистер подбирает ему замену.
+// Вызывать под s.jm.
+func (s *service) markFailed(trackID int) *failure {
+	f, ok := s.failures[trackID]
+
+	if !ok {
+		f = &failure{}
+		s.failures[trackID] = f
+	}
+
+	f.attempts++
+	f.retryAt = time.Now().Add(retryDelay(f.attempts))
+
+	if f.attempts >= maxTrackFailures {
+		f.retryAt = time.Now().Add(unavailableRetry)
+
+		s.state.Tracks.Lock()
+		s.state.Tracks.SetUnavailable(trackID)
+		s.state.Tracks.Unlock()
+
+		s.logger.Warn().Int("trackId", trackID).Int("attempts", f.attempts).Msg("track marked unavailable")
+	}
+
+	return f
+}
+
+// markSucceeded сбрасывает неудачи трека. Вызывать под s.jm.
+func (s *service) markSucceeded(trackID int) {
+	delete(s.failures, trackID)
+
+	s.state.Tracks.Lock()
+	s.state.Tracks.SetAvailable(trackID)
+	s.state.Tracks.Unlock()
+
+	s.lookaheadReported = false
+}
+
+// retryPending - трек недавно не скачался и ждет повтора. Вызывать под s.jm.
+func (s *service) retryPending(trackID int) bool {
+	f, ok := s.failures[trackID]
+
+	return ok && time.Now().Before(f.retryAt)
+}
+
+// reviveUnavailable дает недоступным трекам еще одну попытку, когда подошло время:
+// трек снова попадает в плейлист, и одна неудача вернет его в недоступные.
+func (s *service) reviveUnavailable() {
+	s.jm.Lock()
+	defer s.jm.Unlock()
+
+	s.state.Tracks.Lock()
+	defer s.state.Tracks.Unlock()
+
+	for trackID := range s.state.Tracks.Unavailable() {
+		f, ok := s.failures[trackID]
+
+		if ok && time.Now().Before(f.retryAt) {
+			continue
+		}
+
+		if ok {
+			f.attempts = maxTrackFailures - 1
+		}
+
+		s.state.Tracks.SetAvailable(trackID)
+
+		s.logger.Debug().Int("trackId", trackID).Msg("unavailable track revived")
+	}
+}
+
+// lookaheadFailing - ни один трек плейлиста не скачан, и все они уже не скачивались.
+// Вызывать под s.jm и Playlist.
+func (s *service) lookaheadFailing() (int, bool) {
+	playlist := s.state.Playlist.Get()
+
+	if len(playlist) == 0 {
+		return 0, false
+	}
+
+	for _, pt := range playlist {
+		if pt.FilePath != "" {
+			return 0, false
+		}
+
+		if _, ok := s.failures[pt.Track.ID]; !ok {
+			return 0, false
+		}
+	}
+
+	return len(playlist), true
+}
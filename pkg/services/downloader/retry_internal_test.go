@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func Test_retryDelay(t *testing.T) {
+	for attempts, limit := range map[int]time.Duration{1: retryMin, 2: 2 * retryMin, 3: 4 * retryMin, 20: retryMax} {
+		for i := 0; i < 100; i++ {
+			if d := retryDelay(attempts); d < limit/2 || d >= limit {
+				t.Fatalf("attempts %d: got %s, want [%s, %s)", attempts, d, limit/2, limit)
+			}
+		}
+	}
+}
+
+func Test_markFailed(t *testing.T) {
+	st := state.NewState()
+	svc := NewService(st, zerolog.Nop(), clockwork.NewRealClock(), func(error) {}, func(domain.CacheCoverage) {}, nil, "", Config{})
+
+	for i := 1; i < maxTrackFailures; i++ {
+		svc.markFailed(1)
+
+		if !svc.retryPending(1) {
+			t.Fatalf("attempt %d: retry not pending", i)
+		}
+
+		if st.Tracks.IsUnavailable(1) {
+			t.Fatalf("attempt %d: unavailable too early", i)
+		}
+	}
+
+	svc.markFailed(1)
+
+	if !st.Tracks.IsUnavailable(1) {
+		t.Fatalf("not unavailable after %d failures", maxTrackFailures)
+	}
+
+	// время повтора подошло - трек снова доступен, но до следующей неудачи
+	svc.failures[1].retryAt = time.Now()
+	svc.reviveUnavailable()
+
+	if st.Tracks.IsUnavailable(1) {
+		t.Fatalf("not revived")
+	}
+
+	svc.markFailed(1)
+
+	if !st.Tracks.IsUnavailable(1) {
+		t.Errorf("not unavailable after failed revival")
+	}
+
+	svc.markSucceeded(1)
+
+	if st.Tracks.IsUnavailable(1) || svc.retryPending(1) {
+		t.Errorf("failures not reset")
+	}
+}
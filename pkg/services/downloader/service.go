@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/cavaliercoder/grab"
+	"github.com/jonboulle/clockwork"
 	"github.com/oklog/run"
 	"github.com/rs/zerolog"
 
@@ -17,97 +20,362 @@ import (
 
 const (
 	checkDuration = time.Second
+	cacheDuration = time.Minute
+	// за сколько до открытия заведения скачивание возобновляется
+	preOpenDownload = 15 * time.Minute
 )
 
-type service struct {
-	cm      sync.Mutex
-	current *current
+type Config struct {
+	// скачивать в фоне всю библиотеку
+	Prefetch bool
+	// сколько треков скачивать одновременно
+	Concurrency int
+	// лимит размера кеша в байтах, отрицательный - без лимита
+	CacheMaxSize int64
+	// лимит скорости скачивания в байтах в секунду, 0 - без лимита
+	RateLimit int64
+	// лимитный трафик: качать только MeteredTracks ближайших треков, без предзагрузки
+	Metered       bool
+	MeteredTracks int
+	// запасные CDN по порядку, на случай недоступности MusicData.CdnURL
+	CdnMirrors []string
+}
 
-	state      *state.State
-	logger     zerolog.Logger
+type service struct {
+	jm sync.Mutex
+	// скачивания по ID трека
+	jobs map[int]*job
+	// неудачные скачивания по ID трека
+	failures map[int]*failure
+	// ошибка "не скачивается весь плейлист" уже показана
+	lookaheadReported bool
+	metered           bool
+	// запросы очистки кеша, выполняются в Run; в ответ - сколько треков удалено
+	clearRequests chan chan int
+
+	client  *grab.Client
+	limiter *rateLimiter
+	mirrors *mirrors
+
+	musicData       *domain.MusicData
+	purgedMusicData *domain.MusicData
+	coverage        domain.CacheCoverage
+	// файлы без записи в индексе проверены
+	adopted bool
+
+	state  *state.State
+	logger zerolog.Logger
+	// часы с серверным временем, по ним плейлистер считает открытие заведения
+	clock      clockwork.Clock
 	errCb      func(error)
+	coverageCb func(domain.CacheCoverage)
+	index      domain.CacheIndex
 	mp3RootDir string
+	config     Config
 }
 
-func NewService(state *state.State, logger zerolog.Logger, errCb func(error), mp3RootDir string) *service {
+func NewService(
+	state *state.State,
+	logger zerolog.Logger,
+	clock clockwork.Clock,
+	errCb func(error),
+	coverageCb func(domain.CacheCoverage),
+	index domain.CacheIndex,
+	mp3RootDir string,
+	config Config,
+) *service {
+	logger = logger.With().Str("service", "downloader").Logger()
+
 	return &service{
-		state:      state,
-		logger:     logger.With().Str("service", "downloader").Logger(),
-		errCb:      errCb,
-		mp3RootDir: mp3RootDir,
+		jobs:          make(map[int]*job),
+		failures:      make(map[int]*failure),
+		clearRequests: make(chan chan int),
+		metered:       config.Metered,
+		client:        newGrabClient(),
+		limiter:       newRateLimiter(config.RateLimit),
+		mirrors:       newMirrors(config.CdnMirrors, logger),
+		state:         state,
+		logger:        logger,
+		clock:         clock,
+		errCb:         errCb,
+		coverageCb:    coverageCb,
+		index:         index,
+		mp3RootDir:    mp3RootDir,
+		config:        config,
 	}
 }
 
 func (s *service) Run(ctx context.Context) error {
-	s.logger.Debug().Msg("starts up")
+	s.logger.Debug().Interface("config", s.config).Msg("starts up")
 	defer s.logger.Debug().Msg("stopped")
 
+	s.cleanupPartials()
+	s.cleanupQuarantine()
+
+	if err := s.index.Load(); err != nil {
+		s.logger.Err(err).Msg("cache index load fail")
+	}
+
 	t := time.NewTicker(checkDuration)
 	defer t.Stop()
 
+	ct := time.NewTicker(cacheDuration)
+	defer ct.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
 		case <-t.C:
+			if s.idle() {
+				continue
+			}
+
+			if !s.adopted {
+				s.adoptFiles()
+			}
+
 			s.checkAndDownload(ctx)
+			s.reportCoverage()
+
+		case <-ct.C:
+			s.maintainCache()
+			s.reviveUnavailable()
+
+		case done := <-s.clearRequests:
+			done <- s.clearCache()
+			s.reportCoverage()
 		}
 	}
 }
 
+// idle - заведение закрыто и откроется не скоро, качать нечего.
+func (s *service) idle() bool {
+	s.state.Venue.RLock()
+	defer s.state.Venue.RUnlock()
+
+	if !s.state.Venue.Closed() {
+		return false
+	}
+
+	opensAt := s.state.Venue.OpensAt()
+
+	return opensAt.IsZero() || opensAt.Sub(s.clock.Now()) > preOpenDownload
+}
+
+// SetRateLimit меняет лимит скорости на ходу, в том числе для идущих скачиваний.
+func (s *service) SetRateLimit(bytesPerSecond int64) {
+	s.logger.Debug().Int64("bytesPerSecond", bytesPerSecond).Msg("rate limit changed")
+
+	s.limiter.SetLimit(bytesPerSecond)
+}
+
+// SetMetered включает режим лимитного трафика, применяется на следующей проверке.
+func (s *service) SetMetered(metered bool) {
+	s.logger.Debug().Bool("metered", metered).Msg("metered mode changed")
+
+	s.jm.Lock()
+	s.metered = metered
+	s.jm.Unlock()
+}
+
+// SetCdnMirrors заменяет список запасных CDN.
+func (s *service) SetCdnMirrors(mirrors []string) {
+	s.logger.Debug().Strs("mirrors", mirrors).Msg("cdn mirrors changed")
+
+	s.mirrors.SetFallbacks(mirrors)
+}
+
+// wanted - трек, который нужно скачать.
+type wanted struct {
+	track          *domain.Track
+	priority       int
+	playlistTracks []*domain.PlaylistTrack
+}
+
 func (s *service) checkAndDownload(ctx context.Context) {
 	s.logger.Debug().Msg("starting checkAndDownload...")
 
-	s.state.Playlist.RLock()
-	defer s.state.Playlist.RUnlock()
+	s.state.MusicData.RLock()
+	s.musicData = s.state.MusicData.Get()
+	s.state.MusicData.RUnlock()
+
+	s.state.Playlist.Lock()
+	defer s.state.Playlist.Unlock()
+
+	s.jm.Lock()
+	defer s.jm.Unlock()
 
-	playlist := s.state.Playlist.Get()
+	list := s.playlistWanted()
 
-	if len(playlist) == 0 {
-		s.logger.Debug().Msg("download skipped (playlist empty)")
-		return
+	// скачивания, ставшие ненужными плейлисту, продолжаются как предзагрузка.
+	// Без предзагрузки и при лимитном трафике они останавливаются, скачанное остается в partialExt-файле
+	for trackID, j := range s.jobs {
+		j.priority = priorityPrefetch
+		j.playlistTracks = nil
+
+		for _, w := range list {
+			if w.track.ID == trackID {
+				j.priority = w.priority
+				j.playlistTracks = w.playlistTracks
+			}
+		}
+
+		if j.priority == priorityPrefetch && !j.canceled && (s.metered || !s.config.Prefetch) {
+			s.logger.Debug().Int("trackId", trackID).Bool("metered", s.metered).Msg("job paused")
+			j.cancel()
+		}
 	}
 
-	var playlistTrack *domain.PlaylistTrack
+	if len(list) == 0 {
+		s.logger.Debug().Msg("playlist download skipped (all tracks loaded)")
 
-	for _, pt := range playlist {
-		if pt.FilePath == "" {
-			playlistTrack = pt
-			break
+		if s.config.Prefetch && !s.metered {
+			list = s.prefetchWanted()
 		}
 	}
 
-	if playlistTrack == nil {
-		s.logger.Debug().Msg("download skipped (all tracks loaded)")
-		return
+	s.dispatch(ctx, list)
+}
+
+// playlistWanted - нескачанные треки плейлиста по приоритету:
+// следующий трек, затем реклама, затем остальные. При лимитном трафике - только
+// MeteredTracks первых. Вызывать под s.jm и Playlist.
+func (s *service) playlistWanted() []*wanted {
+	var list []*wanted
+
+	byTrackID := make(map[int]*wanted)
+
+	for i, pt := range s.state.Playlist.Get() {
+		if pt.FilePath != "" {
+			continue
+		}
+
+		// трек уже в кеше
+		if filePath, ok := s.index.Lookup(pt.Track.ID, pt.Track.MP3URL); ok {
+			s.logger.Debug().Int("trackId", pt.Track.ID).Str("filePath", filePath).Msg("cache hit")
+
+			s.state.Playlist.SetDownloadProgress(pt, progress.Passed)
+			s.state.Playlist.SetFilePath(pt, filePath)
+
+			continue
+		}
+
+		if s.retryPending(pt.Track.ID) {
+			continue
+		}
+
+		if s.metered && i >= s.config.MeteredTracks {
+			continue
+		}
+
+		priority := priorityLookahead
+
+		switch {
+		case i == 0:
+			priority = priorityNext
+		case pt.Type == domain.PlaylistTrackTypeAd:
+			priority = priorityAd
+		}
+
+		if w, ok := byTrackID[pt.Track.ID]; ok {
+			w.playlistTracks = append(w.playlistTracks, pt)
+
+			if priority < w.priority {
+				w.priority = priority
+			}
+
+			continue
+		}
+
+		w := &wanted{track: pt.Track, priority: priority, playlistTracks: []*domain.PlaylistTrack{pt}}
+		byTrackID[pt.Track.ID] = w
+		list = append(list, w)
 	}
 
-	s.cm.Lock()
-	defer s.cm.Unlock()
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].priority < list[j].priority
+	})
+
+	return list
+}
+
+// dispatch раздает скачивания свободным воркерам. Если воркеров нет, более важный трек
+// вытесняет наименее важное скачивание, которое потом докачается с места остановки.
+// Вызывать под s.jm.
+func (s *service) dispatch(ctx context.Context, list []*wanted) {
+	for _, w := range list {
+		if _, running := s.jobs[w.track.ID]; running {
+			continue
+		}
+
+		if len(s.jobs) >= s.config.Concurrency {
+			victim := s.lowestPriorityJob()
+
+			if victim == nil || victim.priority <= w.priority {
+				return
+			}
+
+			s.logger.Debug().Int("trackId", victim.track.ID).
+				Int("priority", victim.priority).
+				Int("byTrackId", w.track.ID).
+				Msg("job preempted")
+
+			// воркер освободится, когда скачивание остановится
+			victim.cancel()
 
-	if s.current != nil {
-		if s.current.playlistTrack == playlistTrack {
 			return
 		}
 
-		s.logger.Debug().Msg("current cancel")
-		s.current.cancel()
+		s.logger.Debug().Int("trackId", w.track.ID).Int("priority", w.priority).Msg("start download...")
+
+		s.start(ctx, &job{
+			track:          w.track,
+			priority:       w.priority,
+			playlistTracks: w.playlistTracks,
+			filePath:       s.index.FilePath(w.track.ID),
+			sources:        s.mirrors.sources(s.cdnURL(), w.track),
+			client:         s.client,
+			limiter:        s.limiter,
+			mirrors:        s.mirrors,
+		})
+	}
+}
+
+func (s *service) cdnURL() string {
+	if s.musicData == nil {
+		return ""
 	}
 
-	s.logger.Debug().Interface("playlistTrack", playlistTrack).Msg("start download...")
+	return s.musicData.CdnURL
+}
+
+func (s *service) lowestPriorityJob() *job {
+	var lowest *job
 
-	cur := &current{
-		playlistTrack: playlistTrack,
-		mp3RootDir:    s.mp3RootDir,
+	for _, j := range s.jobs {
+		if j.canceled {
+			continue
+		}
+
+		if lowest == nil || j.priority > lowest.priority {
+			lowest = j
+		}
 	}
 
-	s.current = cur
+	return lowest
+}
+
+// start запускает скачивание. Вызывать под s.jm.
+func (s *service) start(ctx context.Context, j *job) {
+	ctx, j.ctxCancel = context.WithCancel(ctx)
+	s.jobs[j.track.ID] = j
 
-	go s.download(ctx, cur)
+	go s.download(ctx, j)
 }
 
-func (s *service) download(ctx context.Context, cur *current) {
+func (s *service) download(ctx context.Context, j *job) {
 	progressCh := make(chan progress.Progress)
 	defer close(progressCh)
 
@@ -124,12 +392,16 @@ func (s *service) download(ctx context.Context, cur *current) {
 				return nil
 
 			case val := <-progressCh:
-				s.logger.Debug().Int("trackId", cur.playlistTrack.Track.ID).
+				s.logger.Debug().Int("trackId", j.track.ID).
 					Stringer("progress", val).
 					Msg("download progress")
 
 				s.state.Playlist.Lock()
-				s.state.Playlist.SetDownloadProgress(cur.playlistTrack, val)
+				s.jm.Lock()
+				for _, pt := range j.playlistTracks {
+					s.state.Playlist.SetDownloadProgress(pt, val)
+				}
+				s.jm.Unlock()
 				s.state.Playlist.Unlock()
 			}
 		}
@@ -145,29 +417,68 @@ func (s *service) download(ctx context.Context, cur *current) {
 
 	// download...
 	g.Add(func() error {
-		filePath, err := cur.download(ctx, progressCh)
+		entry, err := j.download(ctx, progressCh)
+
+		if err == nil {
+			if err = s.index.Put(entry); err != nil {
+				err = fmt.Errorf("cannot put to cache index: %w", err)
+			}
+		}
+
+		s.state.Playlist.Lock()
+		defer s.state.Playlist.Unlock()
+
+		s.jm.Lock()
+		defer s.jm.Unlock()
+
+		delete(s.jobs, j.track.ID)
 
 		if err != nil {
-			s.logger.Err(err).Interface("playlistTrack", cur.playlistTrack).
+			s.logger.Err(err).Int("trackId", j.track.ID).
+				Int("priority", j.priority).
 				Msg("mp3 download error")
 
-			return fmt.Errorf("mp3 download error: %w", err)
+			// вытеснение - не ошибка
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			f := s.markFailed(j.track.ID)
+
+			s.logger.Debug().Int("trackId", j.track.ID).
+				Int("attempts", f.attempts).
+				Time("retryAt", f.retryAt).
+				Msg("download retry scheduled")
+
+			// испорченный файл показываем один раз: сервер отдает не то, что должен
+			var corruptedErr *CorruptedFileError
+
+			if errors.As(err, &corruptedErr) && f.attempts == 1 {
+				return err
+			}
+
+			// единичные ошибки пользователю не показываем, только когда не скачивается весь плейлист
+			if tracks, failing := s.lookaheadFailing(); failing && !s.lookaheadReported {
+				s.lookaheadReported = true
+
+				return &LookaheadFailingError{Tracks: tracks, Err: err}
+			}
+
+			return nil
 		}
 
-		s.logger.Debug().Interface("playlistTrack", cur.playlistTrack).
-			Str("filePath", filePath).
-			Msg("mp3 downloaded")
+		s.markSucceeded(j.track.ID)
 
-		s.state.Playlist.Lock()
-		s.state.Playlist.SetDownloadProgress(cur.playlistTrack, progress.Passed)
-		s.state.Playlist.SetFilePath(cur.playlistTrack, filePath)
-		s.state.Playlist.Unlock()
+		s.logger.Info().Int("trackId", j.track.ID).
+			Int("priority", j.priority).
+			Interface("entry", entry).
+			Str("servedBy", j.servedBy.url).
+			Msg("mp3 downloaded")
 
-		s.cm.Lock()
-		if s.current == cur {
-			s.current = nil
+		for _, pt := range j.playlistTracks {
+			s.state.Playlist.SetDownloadProgress(pt, progress.Passed)
+			s.state.Playlist.SetFilePath(pt, j.filePath)
 		}
-		s.cm.Unlock()
 
 		return nil
 	}, func(err error) {})
@@ -0,0 +1,311 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/cache"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+// newTestService - загрузчик с кешем в каталоге dir и треками на cdnURL.
+func newTestService(dir, cdnURL string, tracks []*domain.Track, config Config) *service {
+	st := state.NewState()
+	st.MusicData.Set(&domain.MusicData{
+		CdnURL:    cdnURL,
+		Intervals: []*domain.MusicDataInterval{{End: 24 * 3600}},
+		Tracks:    tracks,
+	})
+
+	if config.Concurrency == 0 {
+		config.Concurrency = 1
+	}
+
+	return NewService(st, zerolog.Nop(), clockwork.NewRealClock(), func(error) {}, func(domain.CacheCoverage) {},
+		cache.NewFileIndex(dir, zerolog.Nop()), dir, config)
+}
+
+// waitFor ждет условия, проверяя его под s.jm.
+func waitFor(t *testing.T, s *service, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		s.jm.Lock()
+		ok := cond()
+		s.jm.Unlock()
+
+		if ok {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("timeout")
+}
+
+func mp3Handler(data []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		http.ServeContent(w, r, "track.mp3", time.Time{}, bytes.NewReader(data))
+	})
+}
+
+// newStallingServer отдает mp3, а stallPath - только начало, пока запрос не отменят или не вызовут release.
+func newStallingServer(data []byte, stallPath string) (*httptest.Server, func()) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == stallPath && r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:1000])
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+
+			return
+		}
+
+		mp3Handler(data).ServeHTTP(w, r)
+	}))
+
+	return server, func() { close(release) }
+}
+
+func Test_dispatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	// 383 кадра - 10 секунд, ~160 КБ
+	data := testMP3(383)
+	server := httptest.NewServer(mp3Handler(data))
+
+	defer server.Close()
+
+	track := &domain.Track{ID: 1, MP3URL: "1.mp3", Duration: 10 * time.Second}
+	svc := newTestService(dir, server.URL, []*domain.Track{track}, Config{RateLimit: 200 << 10, Metered: true, MeteredTracks: 1})
+
+	if !svc.metered {
+		t.Errorf("metered from config not applied")
+	}
+
+	pt := &domain.PlaylistTrack{Track: track, Type: domain.PlaylistTrackTypeBackground}
+	svc.state.Playlist.Append(pt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startedAt := time.Now()
+
+	svc.checkAndDownload(ctx)
+
+	waitFor(t, svc, func() bool { return len(svc.jobs) == 0 })
+
+	// лимит 200 КБ/с без начального запаса
+	if elapsed := time.Since(startedAt); elapsed < 500*time.Millisecond {
+		t.Errorf("got download in %s, rate limit not applied", elapsed)
+	}
+
+	e, ok := svc.index.Entry(1)
+
+	if !ok || e.Size != int64(len(data)) {
+		t.Fatalf("got entry %+v, %v", e, ok)
+	}
+
+	svc.state.Playlist.RLock()
+	filePath := pt.FilePath
+	svc.state.Playlist.RUnlock()
+
+	if filePath != svc.index.FilePath(1) {
+		t.Errorf("got filePath %q, want %q", filePath, svc.index.FilePath(1))
+	}
+
+	if _, err := os.Stat(filePath + partialExt); !os.IsNotExist(err) {
+		t.Errorf("partial file left: %v", err)
+	}
+
+	svc.SetRateLimit(0)
+
+	if got := svc.limiter.Limit(); got != 0 {
+		t.Errorf("got limit %d after SetRateLimit, want 0", got)
+	}
+}
+
+func Test_idle(t *testing.T) {
+	// часы сервера на 6 часов впереди устройства
+	now := time.Now().Add(6 * time.Hour)
+	st := state.NewState()
+	svc := NewService(st, zerolog.Nop(), clockwork.NewFakeClockAt(now), func(error) {}, func(domain.CacheCoverage) {},
+		nil, "", Config{})
+
+	for opensIn, want := range map[time.Duration]bool{10 * time.Minute: false, time.Hour: true} {
+		st.Venue.Set(true, now.Add(opensIn))
+
+		if got := svc.idle(); got != want {
+			t.Errorf("opens in %s: got idle %v, want %v", opensIn, got, want)
+		}
+	}
+}
+
+func Test_dispatch_preemption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	data := testMP3(383)
+	server, release := newStallingServer(data, "/2.mp3")
+
+	defer server.Close()
+	defer release()
+
+	next := &domain.Track{ID: 1, MP3URL: "1.mp3", Duration: 10 * time.Second}
+	prefetch := &domain.Track{ID: 2, MP3URL: "2.mp3", Duration: 10 * time.Second}
+	svc := newTestService(dir, server.URL, []*domain.Track{next, prefetch}, Config{Prefetch: true, CacheMaxSize: -1})
+	svc.musicData = svc.state.MusicData.Get()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc.jm.Lock()
+	svc.dispatch(ctx, []*wanted{{track: prefetch, priority: priorityPrefetch}})
+	victim := svc.jobs[prefetch.ID]
+	svc.jm.Unlock()
+
+	partialPath := svc.index.FilePath(prefetch.ID) + partialExt
+
+	waitFor(t, svc, func() bool {
+		fi, err := os.Stat(partialPath)
+		return err == nil && fi.Size() > 0
+	})
+
+	svc.jm.Lock()
+
+	// менее важное скачивание не вытесняет
+	svc.dispatch(ctx, []*wanted{{track: &domain.Track{ID: 3}, priority: priorityPrefetch}})
+
+	if victim.canceled || len(svc.jobs) != 1 {
+		t.Fatalf("prefetch job preempted by equal priority")
+	}
+
+	svc.dispatch(ctx, []*wanted{{track: next, priority: priorityNext}})
+
+	if !victim.canceled {
+		t.Fatalf("prefetch job not preempted")
+	}
+
+	// воркер еще занят остановленным скачиванием
+	if _, ok := svc.jobs[next.ID]; ok {
+		t.Fatalf("next track started before worker freed")
+	}
+
+	svc.jm.Unlock()
+
+	waitFor(t, svc, func() bool { return len(svc.jobs) == 0 })
+
+	svc.jm.Lock()
+	svc.dispatch(ctx, []*wanted{{track: next, priority: priorityNext}})
+	svc.jm.Unlock()
+
+	waitFor(t, svc, func() bool { return len(svc.jobs) == 0 })
+
+	if e, ok := svc.index.Entry(next.ID); !ok || e.Size != int64(len(data)) {
+		t.Errorf("next track not downloaded: %+v, %v", e, ok)
+	}
+
+	if _, ok := svc.index.Entry(prefetch.ID); ok {
+		t.Errorf("preempted track in cache")
+	}
+
+	svc.jm.Lock()
+	defer svc.jm.Unlock()
+
+	// остановленное скачивание - не неудача
+	if svc.retryPending(prefetch.ID) {
+		t.Errorf("preempted track scheduled for retry")
+	}
+
+	// недокачанный кусок остается для докачки
+	if fi, err := os.Stat(partialPath); err != nil || fi.Size() == 0 {
+		t.Errorf("partial file of preempted track: %v", err)
+	}
+}
+
+func Test_checkAndDownload_leftPlaylist(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(strconv.FormatBool(prefetch), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "downloader")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.RemoveAll(dir)
+
+			server, release := newStallingServer(testMP3(383), "/1.mp3")
+
+			defer server.Close()
+			defer release()
+
+			track := &domain.Track{ID: 1, MP3URL: "1.mp3", Duration: 10 * time.Second}
+			svc := newTestService(dir, server.URL, []*domain.Track{track}, Config{Prefetch: prefetch, CacheMaxSize: -1})
+			svc.state.Playlist.Append(&domain.PlaylistTrack{Track: track, Type: domain.PlaylistTrackTypeBackground})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			svc.checkAndDownload(ctx)
+
+			svc.jm.Lock()
+			j := svc.jobs[track.ID]
+			svc.jm.Unlock()
+
+			if j == nil {
+				t.Fatal("job not started")
+			}
+
+			// трек ушел из плейлиста
+			svc.state.Playlist.Lock()
+			svc.state.Playlist.Shift()
+			svc.state.Playlist.Unlock()
+
+			svc.checkAndDownload(ctx)
+
+			svc.jm.Lock()
+			defer svc.jm.Unlock()
+
+			if j.canceled == prefetch {
+				t.Errorf("got canceled %v with prefetch %v", j.canceled, prefetch)
+			}
+
+			if j.priority != priorityPrefetch {
+				t.Errorf("got priority %d, want prefetch", j.priority)
+			}
+		})
+	}
+}
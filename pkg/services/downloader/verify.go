@@ -0,0 +1,202 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cavaliercoder/grab"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/mp3"
+)
+
+const (
+	// файлы меньше - заведомо не трек
+	minFileSize = 1024
+	// допустимое расхождение длительности файла с Track.Duration: доля и минимум
+	durationTolerance    = 0.05
+	minDurationTolerance = 2 * time.Second
+
+	quarantineDirName = "q"
+	quarantineTTL     = 7 * 24 * time.Hour
+)
+
+var errUnexpectedContentType = errors.New("unexpected content type")
+
+type CorruptedFileError struct {
+	TrackID int
+	Err     error
+}
+
+func (e *CorruptedFileError) Error() string {
+	return fmt.Sprintf("corrupted mp3 file: {trackId: %d, reason: %v}", e.TrackID, e.Err)
+}
+
+func (e *CorruptedFileError) Unwrap() error {
+	return e.Err
+}
+
+// checkContentType отсекает ответы, которые не могут быть mp3, до начала скачивания.
+func checkContentType(resp *grab.Response) error {
+	if resp.HTTPResponse == nil {
+		return nil
+	}
+
+	contentType := strings.ToLower(resp.HTTPResponse.Header.Get("Content-Type"))
+
+	switch {
+	case contentType == "",
+		strings.HasPrefix(contentType, "audio/"),
+		strings.HasPrefix(contentType, "application/octet-stream"),
+		strings.HasPrefix(contentType, "binary/octet-stream"):
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errUnexpectedContentType, contentType)
+}
+
+// verifyFile проверяет размер и кадры mp3, возвращает размер файла.
+// expectedSize <= 0 - размер неизвестен.
+func verifyFile(filePath string, expectedSize int64, track *domain.Track) (int64, error) {
+	fi, err := os.Stat(filePath)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot os.Stat: %w, filePath: %s", err, filePath)
+	}
+
+	if fi.Size() < minFileSize {
+		return 0, fmt.Errorf("file too small: %d bytes", fi.Size())
+	}
+
+	if expectedSize > 0 && fi.Size() != expectedSize {
+		return 0, fmt.Errorf("size mismatch: %d bytes, expected: %d", fi.Size(), expectedSize)
+	}
+
+	info, err := mp3.ReadFile(filePath)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if track.Duration > 0 {
+		tolerance := time.Duration(float64(track.Duration) * durationTolerance)
+
+		if tolerance < minDurationTolerance {
+			tolerance = minDurationTolerance
+		}
+
+		diff := info.Duration - track.Duration
+
+		if diff < -tolerance || diff > tolerance {
+			return 0, fmt.Errorf("duration mismatch: %s, expected: %s", info.Duration, track.Duration)
+		}
+	}
+
+	return fi.Size(), nil
+}
+
+// quarantine убирает испорченный файл из кеша, оставляя последнюю копию для разбора.
+func quarantine(filePath string, trackID int) error {
+	dir := path.Join(path.Dir(filePath), quarantineDirName)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot os.MkdirAll: %w, dir: %s", err, dir)
+	}
+
+	to := path.Join(dir, strconv.Itoa(trackID))
+
+	if err := os.Rename(filePath, to); err != nil {
+		return fmt.Errorf("cannot os.Rename: %w, from: %s, to: %s", err, filePath, to)
+	}
+
+	return nil
+}
+
+// adoptFiles добавляет в индекс файлы треков без записи, скачанные до появления индекса.
+// Прежний загрузчик писал прямо в файл трека, поэтому файл проверяется как после скачивания,
+// не прошедший проверку - в карантин. Файлы треков не из музыкальных настроек проверить
+// не с чем, они удаляются. До загрузки музыкальных настроек ничего не делает.
+func (s *service) adoptFiles() {
+	s.state.MusicData.RLock()
+	musicData := s.state.MusicData.Get()
+	s.state.MusicData.RUnlock()
+
+	if musicData == nil {
+		return
+	}
+
+	s.adopted = true
+
+	files, err := ioutil.ReadDir(s.mp3RootDir)
+
+	if err != nil {
+		s.logger.Err(err).Str("mp3RootDir", s.mp3RootDir).Msg("adopt files: read dir fail")
+		return
+	}
+
+	tracks := make(map[int]*domain.Track, len(musicData.Tracks)+len(musicData.Ads))
+
+	for _, track := range musicData.Tracks {
+		tracks[track.ID] = track
+	}
+
+	for _, ad := range musicData.Ads {
+		if ad.Track != nil {
+			tracks[ad.Track.ID] = ad.Track
+		}
+	}
+
+	for _, fi := range files {
+		trackID, err := strconv.Atoi(fi.Name())
+
+		if err != nil || fi.IsDir() {
+			continue
+		}
+
+		if _, ok := s.index.Entry(trackID); ok {
+			continue
+		}
+
+		filePath := path.Join(s.mp3RootDir, fi.Name())
+		track, ok := tracks[trackID]
+
+		if !ok {
+			if err := os.Remove(filePath); err != nil {
+				s.logger.Err(err).Str("filePath", filePath).Msg("adopt files: remove fail")
+			}
+
+			continue
+		}
+
+		size, err := verifyFile(filePath, 0, track)
+
+		if err != nil {
+			s.logger.Warn().Err(err).Int("trackId", trackID).Msg("legacy file corrupted")
+
+			if err := quarantine(filePath, trackID); err != nil {
+				_ = os.Remove(filePath)
+			}
+
+			continue
+		}
+
+		if err := s.index.Put(&domain.CacheEntry{
+			TrackID:      trackID,
+			FileName:     fi.Name(),
+			URL:          track.MP3URL,
+			Size:         size,
+			DownloadedAt: fi.ModTime(),
+		}); err != nil {
+			s.logger.Err(err).Int("trackId", trackID).Msg("adopt files: index put fail")
+			continue
+		}
+
+		s.logger.Debug().Int("trackId", trackID).Msg("legacy file adopted")
+	}
+}
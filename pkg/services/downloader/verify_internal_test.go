@@ -0,0 +1,130 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// testMP3 - n кадров MPEG1 Layer III 128 кбит/с 44100 Гц, по 417 байт и 1152 сэмпла
+func testMP3(n int) []byte {
+	data := make([]byte, 0, n*417)
+
+	for i := 0; i < n; i++ {
+		data = append(data, 0xFF, 0xFB, 0x90, 0x00)
+		data = append(data, make([]byte, 413)...)
+	}
+
+	return data
+}
+
+func Test_verifyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	// 383 кадра - 10 секунд
+	mp3Data := testMP3(383)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		expectedSize int64
+		duration     time.Duration
+		wantErr      bool
+	}{
+		{name: "valid", data: mp3Data, expectedSize: int64(len(mp3Data)), duration: 10 * time.Second},
+		{name: "unknown size and duration", data: mp3Data},
+		{name: "html error page", data: append([]byte("<html>"), make([]byte, minFileSize)...), wantErr: true},
+		{name: "too small", data: mp3Data[:minFileSize-1], wantErr: true},
+		{name: "size mismatch", data: mp3Data, expectedSize: int64(len(mp3Data)) + 1, wantErr: true},
+		{name: "duration mismatch", data: mp3Data, duration: 20 * time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := path.Join(dir, "1.part")
+
+			if err := ioutil.WriteFile(filePath, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := verifyFile(filePath, tt.expectedSize, &domain.Track{ID: 1, Duration: tt.duration})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_adoptFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downloader")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	// 383 кадра - 10 секунд
+	mp3Data := testMP3(383)
+	files := map[string][]byte{
+		"1": mp3Data,
+		// недокачан прежним загрузчиком
+		"2": mp3Data[:len(mp3Data)/3],
+		// трека нет в музыкальных настройках
+		"3":      mp3Data,
+		"4.part": mp3Data,
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tracks := []*domain.Track{
+		{ID: 1, MP3URL: "1.mp3", Duration: 10 * time.Second},
+		{ID: 2, MP3URL: "2.mp3", Duration: 10 * time.Second},
+		{ID: 4, MP3URL: "4.mp3", Duration: 10 * time.Second},
+	}
+	svc := newTestService(dir, "", tracks, Config{})
+	svc.adoptFiles()
+
+	if !svc.adopted {
+		t.Errorf("not marked adopted")
+	}
+
+	if filePath, ok := svc.index.Lookup(1, "1.mp3"); !ok || filePath != svc.index.FilePath(1) {
+		t.Errorf("valid file not adopted: %q, %v", filePath, ok)
+	}
+
+	if _, ok := svc.index.Lookup(1, "other.mp3"); ok {
+		t.Errorf("adopted file matched other url")
+	}
+
+	for _, trackID := range []int{2, 3, 4} {
+		if _, ok := svc.index.Entry(trackID); ok {
+			t.Errorf("track %d adopted", trackID)
+		}
+	}
+
+	if _, err := os.Stat(path.Join(dir, quarantineDirName, "2")); err != nil {
+		t.Errorf("truncated file not quarantined: %v", err)
+	}
+
+	for name, want := range map[string]bool{"2": false, "3": false, "4.part": true} {
+		if _, err := os.Stat(path.Join(dir, name)); os.IsNotExist(err) == want {
+			t.Errorf("%s: got exists %v, want %v", name, !want, want)
+		}
+	}
+}
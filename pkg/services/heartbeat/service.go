@@ -0,0 +1,70 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/utils"
+)
+
+// без связи пауза между попытками растет до maxBackoff
+const maxBackoff = 30 * time.Minute
+
+// service периодически отправляет состояние плеера на сервер.
+type service struct {
+	sender   domain.HeartbeatSender
+	status   func() *domain.DeviceStatus
+	logger   zerolog.Logger
+	interval time.Duration
+}
+
+// NewService - status собирает состояние плеера на момент отправки.
+func NewService(
+	sender domain.HeartbeatSender,
+	status func() *domain.DeviceStatus,
+	logger zerolog.Logger,
+	interval time.Duration,
+) *service {
+	return &service{
+		sender:   sender,
+		status:   status,
+		logger:   logger.With().Str("service", "heartbeat").Logger(),
+		interval: interval,
+	}
+}
+
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Dur("interval", s.interval).Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	failures := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-t.C:
+			if err := s.sender.Send(ctx, s.status()); err != nil {
+				failures++
+
+				s.logger.Warn().Err(err).Int("failures", failures).Msg("heartbeat send fail")
+			} else {
+				failures = 0
+			}
+
+			t.Reset(s.nextDelay(failures))
+		}
+	}
+}
+
+// nextDelay - интервал, после неудач удваивается до maxBackoff.
+func (s *service) nextDelay(failures int) time.Duration {
+	return utils.Backoff(failures+1, s.interval, maxBackoff)
+}
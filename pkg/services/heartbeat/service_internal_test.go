@@ -0,0 +1,32 @@
+package heartbeat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func Test_nextDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{name: "online", interval: 5 * time.Minute, failures: 0, want: 5 * time.Minute},
+		{name: "first failure", interval: 5 * time.Minute, failures: 1, want: 10 * time.Minute},
+		{name: "capped", interval: 5 * time.Minute, failures: 10, want: maxBackoff},
+		{name: "interval above cap", interval: time.Hour, failures: 3, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, nil, zerolog.Nop(), tt.interval)
+
+			if got := svc.nextDelay(tt.failures); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package player
+
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const (
+	volumeMin = 0
+	volumeMax = 1
+)
+
+// crossFade запускает следующий трек за crossFadeDuration до конца текущего:
+// следующий нарастает, текущий затухает и останавливается, когда доиграет.
+func (s *service) crossFade() {
+	next := s.peekReady()
+
+	if next == nil {
+		return
+	}
+
+	d := s.crossFadeDuration(s.current.item, next)
+
+	if d <= 0 {
+		return
+	}
+
+	remaining := s.current.item.Track.Duration - s.position(s.current)
+
+	if remaining > d {
+		return
+	}
+
+	prev := s.current
+
+	if !s.startNext(remaining) {
+		return
+	}
+
+	s.logger.Debug().Int("id", prev.id).Dur("duration", remaining).Msg("fade out")
+
+	if err := s.output.Ramp(prev.id, volumeMax, volumeMin, remaining); err != nil {
+		s.logger.Err(err).Int("id", prev.id).Msg("output ramp fail")
+	}
+
+	s.fading = append(s.fading, prev)
+}
+
+// crossFadeDuration - длительность перехода между треками.
+// Трек короче двух длительностей кроссфейда не может одновременно нарастать и затухать,
+// поэтому переход ограничивается половиной более короткого трека.
+func (s *service) crossFadeDuration(current, next *domain.PlaylistTrack) time.Duration {
+	s.state.PlayerInfo.RLock()
+	playerInfo := s.state.PlayerInfo.Get()
+	s.state.PlayerInfo.RUnlock()
+
+	if playerInfo == nil || !playerInfo.HasCrossFade {
+		return 0
+	}
+
+	if s.crossFadeExcludeAds &&
+		(current.Type == domain.PlaylistTrackTypeAd || next.Type == domain.PlaylistTrackTypeAd) {
+		return 0
+	}
+
+	d := playerInfo.CrossFadeDuration
+
+	for _, item := range []*domain.PlaylistTrack{current, next} {
+		if half := item.Track.Duration / 2; d > half {
+			d = half
+		}
+	}
+
+	return d
+}
+
+// stopFaded останавливает затухшие треки.
+func (s *service) stopFaded() {
+	fading := s.fading[:0]
+
+	for _, pb := range s.fading {
+		if s.position(pb) < pb.item.Track.Duration {
+			fading = append(fading, pb)
+			continue
+		}
+
+		s.stopPlayback(pb, false)
+	}
+
+	s.fading = fading
+}
@@ -0,0 +1,83 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+func setCrossFade(svc *service, d time.Duration) {
+	svc.state.PlayerInfo.Set(&domain.PlayerInfo{HasCrossFade: true, CrossFadeDuration: d})
+}
+
+func Test_crossFade(t *testing.T) {
+	t.Run("overlaps tracks", func(t *testing.T) {
+		svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+		setCrossFade(svc, 5*time.Second)
+
+		svc.tick()
+		clock.Advance(54 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1")
+
+		clock.Advance(time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "play 2 track 2", "ramp 2 0→1 5s", "ramp 1 1→0 5s")
+
+		if got := svc.state.Player.Current().Track.ID; got != 2 {
+			t.Errorf("got current track %d, want 2", got)
+		}
+
+		clock.Advance(5 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "play 2 track 2", "ramp 2 0→1 5s", "ramp 1 1→0 5s", "stop 1")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+		svc.state.PlayerInfo.Set(&domain.PlayerInfo{HasCrossFade: false, CrossFadeDuration: 5 * time.Second})
+
+		svc.tick()
+		clock.Advance(59 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1")
+	})
+
+	t.Run("track shorter than twice crossfade", func(t *testing.T) {
+		svc, clock, output := newTestService(
+			newTestItem(1, time.Minute, "/m/1"),
+			newTestItem(2, 6*time.Second, "/m/2"),
+			newTestItem(3, time.Minute, "/m/3"),
+		)
+		setCrossFade(svc, 5*time.Second)
+
+		svc.tick()
+		clock.Advance(57 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "play 2 track 2", "ramp 2 0→1 3s", "ramp 1 1→0 3s")
+
+		// нарастание и затухание короткого трека не пересекаются
+		clock.Advance(3 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "play 2 track 2", "ramp 2 0→1 3s", "ramp 1 1→0 3s",
+			"stop 1", "play 3 track 3", "ramp 3 0→1 3s", "ramp 2 1→0 3s")
+	})
+
+	t.Run("ads excluded", func(t *testing.T) {
+		ad := newTestItem(2, 30*time.Second, "/m/2")
+		ad.Type = domain.PlaylistTrackTypeAd
+
+		svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), ad)
+		setCrossFade(svc, 5*time.Second)
+
+		svc.tick()
+		clock.Advance(59 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1")
+
+		clock.Advance(time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "stop 1", "play 2 track 2")
+	})
+}
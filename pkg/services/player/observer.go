@@ -0,0 +1,29 @@
+package player
+
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// Finish - как закончилось воспроизведение трека.
+type Finish struct {
+	At        time.Time
+	PlayedFor time.Duration
+	// пропущен командой Next
+	Skipped bool
+	// остановлен до конца: пропуск, Stop, закрытие заведения
+	Interrupted bool
+}
+
+// Observer получает события воспроизведения. Вызывается под блокировкой плеера,
+// поэтому не должен обращаться к плееру и долго работать.
+type Observer interface {
+	TrackStarted(item *domain.PlaylistTrack, at time.Time)
+	TrackFinished(item *domain.PlaylistTrack, finish Finish)
+}
+
+// AddObserver - вызывать до Run.
+func (s *service) AddObserver(observer Observer) {
+	s.observers = append(s.observers, observer)
+}
@@ -0,0 +1,33 @@
+package player
+
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// Output - устройство вывода звука (нативный плеер на мобильных, заглушка в тестах и CLI).
+// id - номер воспроизведения, уникальный в рамках запуска.
+// При кроссфейде одновременно играют два воспроизведения, громкость каждого меняет Ramp.
+// SetVolume плавно за d меняет общую громкость, она умножается на громкость воспроизведений.
+type Output interface {
+	Play(id int, item *domain.PlaylistTrack, offset time.Duration) error
+	Ramp(id int, from, to float64, d time.Duration) error
+	SetVolume(volume float64, d time.Duration) error
+	Pause() error
+	Resume() error
+	Stop(id int) error
+}
+
+type nullOutput struct{}
+
+func NewNullOutput() *nullOutput {
+	return &nullOutput{}
+}
+
+func (n *nullOutput) Play(int, *domain.PlaylistTrack, time.Duration) error { return nil }
+func (n *nullOutput) Ramp(int, float64, float64, time.Duration) error      { return nil }
+func (n *nullOutput) SetVolume(float64, time.Duration) error               { return nil }
+func (n *nullOutput) Pause() error                                         { return nil }
+func (n *nullOutput) Resume() error                                        { return nil }
+func (n *nullOutput) Stop(int) error                                       { return nil }
@@ -0,0 +1,343 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+const tickDuration = 250 * time.Millisecond
+
+// playback - трек, отданный в Output.
+type playback struct {
+	id   int
+	item *domain.PlaylistTrack
+
+	// позиция на момент startedAt
+	offset    time.Duration
+	startedAt time.Time
+}
+
+type service struct {
+	mu      sync.Mutex
+	status  domain.PlayerStatus
+	current *playback
+	// затухающие при кроссфейде
+	fading []*playback
+	lastID int
+	// громкость, отданная в Output, и громкость расписания в ней
+	volume     float64
+	baseVolume float64
+	volumeSet  bool
+	userVolume float64
+
+	state               *state.State
+	logger              zerolog.Logger
+	clock               clockwork.Clock
+	output              Output
+	errCb               func(error)
+	crossFadeExcludeAds bool
+	observers           []Observer
+}
+
+func NewService(
+	state *state.State,
+	logger zerolog.Logger,
+	clock clockwork.Clock,
+	output Output,
+	errCb func(error),
+	crossFadeExcludeAds bool,
+) *service {
+	return &service{
+		status:              domain.PlayerStatusPlaying,
+		userVolume:          volumeMax,
+		state:               state,
+		logger:              logger.With().Str("service", "player").Logger(),
+		clock:               clock,
+		output:              output,
+		errCb:               errCb,
+		crossFadeExcludeAds: crossFadeExcludeAds,
+	}
+}
+
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	t := time.NewTicker(tickDuration)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Stop()
+
+			return ctx.Err()
+
+		case <-t.C:
+			s.tick()
+		}
+	}
+}
+
+func (s *service) Play() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.status {
+	case domain.PlayerStatusPlaying:
+		return
+
+	case domain.PlayerStatusPaused:
+		if s.current != nil {
+			if err := s.output.Resume(); err != nil {
+				s.logger.Err(err).Msg("output resume fail")
+			}
+		}
+
+		for _, pb := range s.playbacks() {
+			pb.startedAt = s.clock.Now()
+		}
+	}
+
+	s.status = domain.PlayerStatusPlaying
+	s.step()
+}
+
+func (s *service) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status != domain.PlayerStatusPlaying {
+		return
+	}
+
+	for _, pb := range s.playbacks() {
+		pb.offset = s.position(pb)
+	}
+
+	if s.current != nil {
+		if err := s.output.Pause(); err != nil {
+			s.logger.Err(err).Msg("output pause fail")
+		}
+	}
+
+	s.status = domain.PlayerStatusPaused
+	s.syncState()
+}
+
+func (s *service) Next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopAll(true)
+
+	s.status = domain.PlayerStatusPlaying
+	s.step()
+}
+
+func (s *service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopAll(false)
+
+	s.status = domain.PlayerStatusStopped
+	s.syncState()
+}
+
+func (s *service) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.step()
+}
+
+// step завершает доигравший трек и запускает следующий. Вызывать под s.mu.
+func (s *service) step() {
+	switch {
+	// статус не меняется: после открытия плеер заиграет сам
+	case s.status == domain.PlayerStatusPlaying && s.venueClosed():
+		if s.current != nil || len(s.fading) > 0 {
+			s.logger.Info().Msg("venue closed, playback stopped")
+			s.stopAll(false)
+		}
+
+	case s.status == domain.PlayerStatusPlaying:
+		s.stopFaded()
+
+		if s.current != nil {
+			if s.position(s.current) >= s.current.item.Track.Duration {
+				s.logger.Debug().Int("trackId", s.current.item.Track.ID).Msg("track finished")
+				s.stopCurrent(false)
+			} else {
+				s.crossFade()
+			}
+		}
+
+		if s.current == nil {
+			s.startNext(0)
+		}
+	}
+
+	s.applyVolume()
+	s.syncState()
+}
+
+func (s *service) venueClosed() bool {
+	s.state.Venue.RLock()
+	defer s.state.Venue.RUnlock()
+
+	return s.state.Venue.Closed()
+}
+
+// startNext запускает следующий трек, fadeIn > 0 - с нарастанием громкости.
+func (s *service) startNext(fadeIn time.Duration) bool {
+	item := s.shiftReady()
+
+	if item == nil {
+		return false
+	}
+
+	s.lastID++
+
+	pb := &playback{
+		id:        s.lastID,
+		item:      item,
+		startedAt: s.clock.Now(),
+	}
+
+	s.logger.Debug().Int("id", pb.id).Interface("playlistTrack", item).Msg("track start")
+
+	if err := s.output.Play(pb.id, item, 0); err != nil {
+		s.logger.Err(err).Interface("playlistTrack", item).Msg("output play fail")
+		s.errCb(fmt.Errorf("output play fail: %w, trackId: %d", err, item.Track.ID))
+
+		return false
+	}
+
+	if fadeIn > 0 {
+		s.logger.Debug().Int("id", pb.id).Dur("duration", fadeIn).Msg("fade in")
+
+		if err := s.output.Ramp(pb.id, volumeMin, volumeMax, fadeIn); err != nil {
+			s.logger.Err(err).Int("id", pb.id).Msg("output ramp fail")
+		}
+	}
+
+	s.current = pb
+
+	for _, o := range s.observers {
+		o.TrackStarted(item, pb.startedAt)
+	}
+
+	return true
+}
+
+// peekReady возвращает первый трек плейлиста, если он скачан.
+func (s *service) peekReady() *domain.PlaylistTrack {
+	s.state.Playlist.RLock()
+	defer s.state.Playlist.RUnlock()
+
+	playlist := s.state.Playlist.Get()
+
+	if len(playlist) == 0 || playlist[0].FilePath == "" {
+		return nil
+	}
+
+	return playlist[0]
+}
+
+// shiftReady забирает первый трек плейлиста, если он скачан.
+func (s *service) shiftReady() *domain.PlaylistTrack {
+	s.state.Playlist.Lock()
+	defer s.state.Playlist.Unlock()
+
+	playlist := s.state.Playlist.Get()
+
+	if len(playlist) == 0 || playlist[0].FilePath == "" {
+		return nil
+	}
+
+	return s.state.Playlist.Shift()
+}
+
+// stopCurrent останавливает текущий трек, skipped - по команде Next.
+func (s *service) stopCurrent(skipped bool) {
+	if s.current == nil {
+		return
+	}
+
+	s.stopPlayback(s.current, skipped)
+	s.current = nil
+}
+
+// stopAll останавливает текущий и затухающие треки.
+func (s *service) stopAll(skipped bool) {
+	for _, pb := range s.fading {
+		s.stopPlayback(pb, false)
+	}
+
+	s.fading = nil
+
+	s.stopCurrent(skipped)
+}
+
+func (s *service) stopPlayback(pb *playback, skipped bool) {
+	if err := s.output.Stop(pb.id); err != nil {
+		s.logger.Err(err).Int("id", pb.id).Msg("output stop fail")
+	}
+
+	finish := Finish{
+		At:          s.clock.Now(),
+		PlayedFor:   s.position(pb),
+		Skipped:     skipped,
+		Interrupted: s.position(pb) < pb.item.Track.Duration,
+	}
+
+	if finish.PlayedFor > pb.item.Track.Duration {
+		finish.PlayedFor = pb.item.Track.Duration
+	}
+
+	for _, o := range s.observers {
+		o.TrackFinished(pb.item, finish)
+	}
+}
+
+func (s *service) playbacks() []*playback {
+	if s.current == nil {
+		return s.fading
+	}
+
+	return append(append([]*playback(nil), s.fading...), s.current)
+}
+
+func (s *service) position(pb *playback) time.Duration {
+	if s.status != domain.PlayerStatusPlaying {
+		return pb.offset
+	}
+
+	return pb.offset + s.clock.Since(pb.startedAt)
+}
+
+func (s *service) syncState() {
+	var (
+		item     *domain.PlaylistTrack
+		position time.Duration
+	)
+
+	if s.current != nil {
+		item = s.current.item
+		position = s.position(s.current)
+	}
+
+	s.state.Player.Lock()
+	s.state.Player.Set(s.status, item, position)
+	s.state.Player.Unlock()
+}
@@ -0,0 +1,206 @@
+package player
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+type fakeOutput struct {
+	calls []string
+	// громкость ведется отдельно, чтобы не мешать проверке остальных вызовов
+	volumes []string
+}
+
+func (f *fakeOutput) Play(id int, item *domain.PlaylistTrack, offset time.Duration) error {
+	f.calls = append(f.calls, fmt.Sprintf("play %d track %d", id, item.Track.ID))
+	return nil
+}
+
+func (f *fakeOutput) Ramp(id int, from, to float64, d time.Duration) error {
+	f.calls = append(f.calls, fmt.Sprintf("ramp %d %.0f→%.0f %s", id, from, to, d))
+	return nil
+}
+
+func (f *fakeOutput) SetVolume(volume float64, d time.Duration) error {
+	f.volumes = append(f.volumes, fmt.Sprintf("%.1f %s", volume, d))
+	return nil
+}
+
+func (f *fakeOutput) Pause() error {
+	f.calls = append(f.calls, "pause")
+	return nil
+}
+
+func (f *fakeOutput) Resume() error {
+	f.calls = append(f.calls, "resume")
+	return nil
+}
+
+func (f *fakeOutput) Stop(id int) error {
+	f.calls = append(f.calls, fmt.Sprintf("stop %d", id))
+	return nil
+}
+
+func newTestService(items ...*domain.PlaylistTrack) (*service, clockwork.FakeClock, *fakeOutput) {
+	st := state.NewState()
+
+	for _, item := range items {
+		st.Playlist.Append(item)
+	}
+
+	clock := clockwork.NewFakeClockAt(time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC))
+	output := &fakeOutput{}
+
+	return NewService(st, zerolog.Nop(), clock, output, func(error) {}, true), clock, output
+}
+
+func newTestItem(id int, duration time.Duration, filePath string) *domain.PlaylistTrack {
+	return &domain.PlaylistTrack{
+		Track:    &domain.Track{ID: id, Duration: duration},
+		Type:     domain.PlaylistTrackTypeBackground,
+		FilePath: filePath,
+	}
+}
+
+func checkCalls(t *testing.T, output *fakeOutput, want ...string) {
+	t.Helper()
+
+	if !reflect.DeepEqual(output.calls, want) {
+		t.Fatalf("got calls %q, want %q", output.calls, want)
+	}
+}
+
+func Test_tick(t *testing.T) {
+	t.Run("waits for file", func(t *testing.T) {
+		item := newTestItem(1, time.Minute, "")
+		svc, _, output := newTestService(item)
+
+		svc.tick()
+		checkCalls(t, output)
+
+		item.FilePath = "/m/1"
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1")
+
+		if len(svc.state.Playlist.Get()) != 0 {
+			t.Errorf("track not shifted from playlist")
+		}
+	})
+
+	t.Run("advances after duration", func(t *testing.T) {
+		svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+
+		svc.tick()
+		clock.Advance(59 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1")
+
+		if got := svc.state.Player.Remaining(); got != time.Second {
+			t.Errorf("got remaining %v, want 1s", got)
+		}
+
+		clock.Advance(time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "stop 1", "play 2 track 2")
+	})
+
+	t.Run("pause freezes position", func(t *testing.T) {
+		svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+
+		svc.tick()
+		clock.Advance(30 * time.Second)
+		svc.Pause()
+		clock.Advance(time.Hour)
+		svc.tick()
+
+		if got := svc.state.Player.Position(); got != 30*time.Second {
+			t.Errorf("got position %v, want 30s", got)
+		}
+
+		svc.Play()
+		clock.Advance(30 * time.Second)
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "pause", "resume", "stop 1", "play 2 track 2")
+	})
+
+	t.Run("venue closed", func(t *testing.T) {
+		svc, _, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+
+		svc.tick()
+		svc.state.Venue.Set(true, time.Time{})
+		svc.tick()
+		svc.tick()
+
+		if got := svc.state.Player.Status(); got != domain.PlayerStatusPlaying {
+			t.Errorf("got status %s, want %s", got, domain.PlayerStatusPlaying)
+		}
+
+		svc.state.Venue.Set(false, time.Time{})
+		svc.tick()
+		checkCalls(t, output, "play 1 track 1", "stop 1", "play 2 track 2")
+	})
+
+	t.Run("next and stop", func(t *testing.T) {
+		svc, _, output := newTestService(newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"))
+
+		svc.tick()
+		svc.Next()
+		svc.Stop()
+		svc.tick()
+
+		if got := svc.state.Player.Status(); got != domain.PlayerStatusStopped {
+			t.Errorf("got status %s, want %s", got, domain.PlayerStatusStopped)
+		}
+
+		checkCalls(t, output, "play 1 track 1", "stop 1", "play 2 track 2", "stop 2")
+	})
+}
+
+type recordingObserver struct {
+	events []string
+}
+
+func (r *recordingObserver) TrackStarted(item *domain.PlaylistTrack, at time.Time) {
+	r.events = append(r.events, fmt.Sprintf("start %d", item.Track.ID))
+}
+
+func (r *recordingObserver) TrackFinished(item *domain.PlaylistTrack, finish Finish) {
+	r.events = append(r.events, fmt.Sprintf("finish %d %s skipped=%v interrupted=%v",
+		item.Track.ID, finish.PlayedFor, finish.Skipped, finish.Interrupted))
+}
+
+func Test_observers(t *testing.T) {
+	svc, clock, _ := newTestService(
+		newTestItem(1, time.Minute, "/m/1"), newTestItem(2, time.Minute, "/m/2"), newTestItem(3, time.Minute, "/m/3"))
+	observer := &recordingObserver{}
+	svc.AddObserver(observer)
+
+	svc.tick()
+	clock.Advance(time.Minute + time.Second)
+	svc.tick()
+	clock.Advance(10 * time.Second)
+	svc.Next()
+	clock.Advance(20 * time.Second)
+	svc.Stop()
+
+	want := []string{
+		"start 1",
+		"finish 1 1m0s skipped=false interrupted=false",
+		"start 2",
+		"finish 2 10s skipped=true interrupted=true",
+		"start 3",
+		"finish 3 20s skipped=false interrupted=true",
+	}
+
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("got events %q, want %q", observer.events, want)
+	}
+}
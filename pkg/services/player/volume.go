@@ -0,0 +1,66 @@
+package player
+
+import (
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// на смене интервала громкость меняется плавно
+const volumeRamp = 5 * time.Second
+
+// SetVolume - громкость, заданная пользователем или с сервера, 0..1. Умножается на громкость расписания.
+func (s *service) SetVolume(volume float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.userVolume = volume
+	s.applyVolume()
+}
+
+// applyVolume отдает в Output громкость расписания с учетом пользовательской, для рекламы - с ее VolumeBoost.
+// Смена интервала или пользовательской громкости растягивается на volumeRamp,
+// реклама меняет громкость сразу на границе трека.
+func (s *service) applyVolume() {
+	base := s.scheduledVolume() * s.userVolume
+	target := base
+
+	if s.current != nil && s.current.item.Type == domain.PlaylistTrackTypeAd && s.current.item.Ad != nil {
+		target += s.current.item.Ad.VolumeBoost
+	}
+
+	if target > volumeMax {
+		target = volumeMax
+	}
+
+	if target < volumeMin {
+		target = volumeMin
+	}
+
+	if s.volumeSet && target == s.volume {
+		return
+	}
+
+	var d time.Duration
+
+	if s.volumeSet && base != s.baseVolume {
+		d = volumeRamp
+	}
+
+	s.logger.Debug().Float64("volume", target).Dur("duration", d).Msg("set volume")
+
+	// не вышло - повторится на следующем тике
+	if err := s.output.SetVolume(target, d); err != nil {
+		s.logger.Err(err).Float64("volume", target).Msg("output set volume fail")
+		return
+	}
+
+	s.volume, s.baseVolume, s.volumeSet = target, base, true
+}
+
+func (s *service) scheduledVolume() float64 {
+	s.state.Venue.RLock()
+	defer s.state.Venue.RUnlock()
+
+	return s.state.Venue.Volume()
+}
@@ -0,0 +1,38 @@
+package player
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+func Test_applyVolume(t *testing.T) {
+	ad := newTestItem(2, time.Minute, "/m/2")
+	ad.Type = domain.PlaylistTrackTypeAd
+	ad.Ad = &domain.Ad{ID: 1, VolumeBoost: 0.3}
+
+	svc, clock, output := newTestService(newTestItem(1, time.Minute, "/m/1"), ad, newTestItem(3, time.Minute, "/m/3"))
+	svc.state.Venue.SetVolume(0.5)
+
+	svc.tick()
+	svc.tick()
+
+	// смена интервала
+	svc.state.Venue.SetVolume(0.8)
+	svc.tick()
+
+	// реклама громче, но не больше максимума
+	clock.Advance(time.Minute)
+	svc.tick()
+
+	clock.Advance(time.Minute)
+	svc.tick()
+
+	want := []string{"0.5 0s", "0.8 5s", "1.0 0s", "0.8 0s"}
+
+	if !reflect.DeepEqual(output.volumes, want) {
+		t.Errorf("got volumes %q, want %q", output.volumes, want)
+	}
+}
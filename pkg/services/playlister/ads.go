@@ -0,0 +1,133 @@
+package playlister
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// сколько храним информацию о запланированных рекламах
+const adSlotsTTL = 48 * time.Hour
+
+// adSlot - конкретный выход рекламы по расписанию.
+type adSlot struct {
+	ad *domain.Ad
+	at time.Time
+}
+
+func (a adSlot) key() string {
+	return adSlotKey(a.ad.ID, a.at)
+}
+
+func (a adSlot) playlistTrack() *domain.PlaylistTrack {
+	return &domain.PlaylistTrack{
+		Track:         a.ad.Track,
+		Type:          domain.PlaylistTrackTypeAd,
+		Ad:            a.ad,
+		AdScheduledAt: a.at,
+	}
+}
+
+// adSlotKey - по местному времени: смена пояса заведения не повторяет уже сыгравшие выходы.
+func adSlotKey(adID int, at time.Time) string {
+	return fmt.Sprintf("%d@%s", adID, at.Format("2006-01-02T15:04:05"))
+}
+
+func playlistTrackAdSlotKey(pt *domain.PlaylistTrack) (string, bool) {
+	if pt.Type != domain.PlaylistTrackTypeAd || pt.Ad == nil {
+		return "", false
+	}
+
+	return adSlotKey(pt.Ad.ID, pt.AdScheduledAt), true
+}
+
+// dueAdSlot возвращает самый ранний еще не запланированный выход рекламы,
+// который должен сыграть на границе трека, начинающегося в at.
+// Реклама играет на первой границе треков после времени по расписанию,
+// одновременные выходы идут подряд (по времени, затем по ID рекламы),
+// опоздавшие больше чем на s.rules.AdMaxDelay отбрасываются.
+func (s *service) dueAdSlot(at time.Time) (adSlot, bool) {
+	for _, slot := range s.adSlotsBetween(at.Add(-adSlotsTTL/2), at) {
+		key := slot.key()
+
+		if _, handled := s.adSlots[key]; handled {
+			continue
+		}
+
+		if s.rules.AdMaxDelay > 0 && at.Sub(slot.at) > s.rules.AdMaxDelay {
+			s.logger.Debug().Int("adId", slot.ad.ID).
+				Time("scheduledAt", slot.at).
+				Time("at", at).
+				Msg("ad slot dropped (too late)")
+
+			s.adSlots[key] = slot.at
+
+			continue
+		}
+
+		return slot, true
+	}
+
+	return adSlot{}, false
+}
+
+// adSlotsBetween возвращает выходы рекламы в промежутке (from, to] по часам пояса from.
+func (s *service) adSlotsBetween(from, to time.Time) []adSlot {
+	var slots []adSlot
+
+	for _, ad := range s.musicData.Ads {
+		if ad.Track == nil {
+			continue
+		}
+
+		day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+
+		for !day.After(to) {
+			if adPlaysOnWeekday(ad, day.Weekday()) {
+				for _, sec := range ad.Times.Times {
+					at := wallTime(day, sec)
+
+					if at.After(from) && !at.After(to) {
+						slots = append(slots, adSlot{ad: ad, at: at})
+					}
+				}
+			}
+
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if !slots[i].at.Equal(slots[j].at) {
+			return slots[i].at.Before(slots[j].at)
+		}
+
+		return slots[i].ad.ID < slots[j].ad.ID
+	})
+
+	return slots
+}
+
+func adPlaysOnWeekday(ad *domain.Ad, weekday time.Weekday) bool {
+	return worksOnWeekday(ad.Times.Days, weekday)
+}
+
+// forgetPlannedAds забывает выходы рекламы, которые стоят в плейлисте,
+// чтобы они были запланированы заново.
+func (s *service) forgetPlannedAds(items []*domain.PlaylistTrack) {
+	for _, pt := range items {
+		if key, ok := playlistTrackAdSlotKey(pt); ok {
+			delete(s.adSlots, key)
+		}
+	}
+}
+
+func (s *service) pruneAdSlots(now time.Time) {
+	for key, at := range s.adSlots {
+		if now.Sub(at) > adSlotsTTL {
+			delete(s.adSlots, key)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package playlister
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+const testAdMaxDelay = 15 * time.Minute
+
+// понедельник
+var adsTestNow = time.Date(2021, time.March, 1, 11, 58, 0, 0, time.UTC)
+
+func newAdsTestService(now time.Time, ads ...*domain.Ad) (*service, clockwork.FakeClock) {
+	tracks := make([]*domain.Track, 0, 10)
+	trackIDs := make([]int, 0, 10)
+
+	for id := 1; id <= 10; id++ {
+		tracks = append(tracks, &domain.Track{ID: id, Duration: 3 * time.Minute})
+		trackIDs = append(trackIDs, id)
+	}
+
+	st := state.NewState()
+	st.MusicData.Set(&domain.MusicData{
+		Ads:       ads,
+		Intervals: []*domain.MusicDataInterval{{Start: 0, End: secondsInDay, TrackIDs: trackIDs}},
+		Tracks:    tracks,
+	})
+
+	clock := clockwork.NewFakeClockAt(now)
+	svc := NewService(st, zerolog.Nop(), clock, Rules{AdMaxDelay: testAdMaxDelay}, "UTC", nil)
+	svc.musicData = st.MusicData.Get()
+
+	return svc, clock
+}
+
+func newTestAd(id int, days []int, times ...int) *domain.Ad {
+	return &domain.Ad{
+		ID:    id,
+		Times: domain.AdTimes{Days: days, Times: times},
+		Track: &domain.Track{ID: 1000 + id, Duration: 30 * time.Second},
+	}
+}
+
+func adPositions(items []*domain.PlaylistTrack) []int {
+	var positions []int
+
+	for i, pt := range items {
+		if pt.Type == domain.PlaylistTrackTypeAd {
+			positions = append(positions, i)
+		}
+	}
+
+	return positions
+}
+
+func Test_updateList_ads(t *testing.T) {
+	noon := 12 * 3600
+
+	t.Run("ad on next track boundary", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow, newTestAd(1, nil, noon))
+		svc.updateList(true)
+
+		items := svc.state.Playlist.Get()
+
+		if len(items) != trackCount {
+			t.Fatalf("got len %d, want %d", len(items), trackCount)
+		}
+
+		// 11:58 - фон, 12:01 - реклама
+		if got := adPositions(items); len(got) != 1 || got[0] != 1 {
+			t.Fatalf("got ad positions %v, want [1]", got)
+		}
+
+		if !items[1].AdScheduledAt.Equal(time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("got scheduledAt %v", items[1].AdScheduledAt)
+		}
+	})
+
+	t.Run("colliding ads queue back-to-back", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow, newTestAd(2, nil, noon), newTestAd(1, nil, noon))
+		svc.updateList(true)
+
+		items := svc.state.Playlist.Get()
+
+		if got := adPositions(items); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+			t.Fatalf("got ad positions %v, want [1 2]", got)
+		}
+
+		if items[1].Ad.ID != 1 || items[2].Ad.ID != 2 {
+			t.Errorf("got ad order %d, %d, want 1, 2", items[1].Ad.ID, items[2].Ad.ID)
+		}
+	})
+
+	t.Run("late ad plays first", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow.Add(10*time.Minute), newTestAd(1, nil, noon))
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 1 || got[0] != 0 {
+			t.Fatalf("got ad positions %v, want [0]", got)
+		}
+	})
+
+	t.Run("too late ad dropped", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow.Add(testAdMaxDelay+3*time.Minute), newTestAd(1, nil, noon))
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 0 {
+			t.Fatalf("got ad positions %v, want none", got)
+		}
+	})
+
+	t.Run("late ad within configured delay", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow.Add(testAdMaxDelay+3*time.Minute), newTestAd(1, nil, noon))
+		svc.rules.AdMaxDelay = 2 * testAdMaxDelay
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 1 || got[0] != 0 {
+			t.Fatalf("got ad positions %v, want [0]", got)
+		}
+	})
+
+	t.Run("late ad without delay limit", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow.Add(3*time.Hour), newTestAd(1, nil, noon))
+		svc.rules.AdMaxDelay = -1
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 1 || got[0] != 0 {
+			t.Fatalf("got ad positions %v, want [0]", got)
+		}
+	})
+
+	t.Run("other weekday", func(t *testing.T) {
+		svc, _ := newAdsTestService(adsTestNow, newTestAd(1, []int{2, 7}, noon))
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 0 {
+			t.Fatalf("got ad positions %v, want none", got)
+		}
+	})
+
+	t.Run("ad inserted into existing playlist once", func(t *testing.T) {
+		svc, clock := newAdsTestService(adsTestNow.Add(-time.Hour), newTestAd(1, nil, noon))
+		svc.updateList(true)
+
+		if got := adPositions(svc.state.Playlist.Get()); len(got) != 0 {
+			t.Fatalf("got ad positions %v, want none", got)
+		}
+
+		clock.Advance(time.Hour)
+		svc.updateList(false)
+		svc.updateList(false)
+
+		items := svc.state.Playlist.Get()
+
+		if len(items) != trackCount {
+			t.Fatalf("got len %d, want %d", len(items), trackCount)
+		}
+
+		if got := adPositions(items); len(got) != 1 || got[0] != 1 {
+			t.Fatalf("got ad positions %v, want [1]", got)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package playlister
+
+import (
+	"sort"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// сколько дней вперед ищется время открытия
+const openingSearchDays = 8
+
+// isOpen - заведение открыто в at: время покрыто интервалом и часами работы.
+func (s *service) isOpen(at time.Time) bool {
+	if len(s.musicData.Intervals) > 0 {
+		if _, ok := s.findIntervalIndex(secondsOfDay(at)); !ok {
+			return false
+		}
+	}
+
+	return s.withinOpeningHours(at)
+}
+
+func (s *service) withinOpeningHours(at time.Time) bool {
+	hours := s.openingHours()
+
+	if len(hours) == 0 {
+		return true
+	}
+
+	sec := secondsOfDay(at)
+	weekday := at.Weekday()
+	yesterday := at.AddDate(0, 0, -1).Weekday()
+
+	for _, oh := range hours {
+		switch {
+		// круглые сутки
+		case oh.Start == oh.End:
+			if worksOnWeekday(oh.Days, weekday) {
+				return true
+			}
+
+		// после полуночи работа относится ко дню начала
+		case oh.Start > oh.End:
+			if sec >= oh.Start && worksOnWeekday(oh.Days, weekday) || sec < oh.End && worksOnWeekday(oh.Days, yesterday) {
+				return true
+			}
+
+		default:
+			if oh.Start <= sec && sec < oh.End && worksOnWeekday(oh.Days, weekday) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// nextOpening - ближайший после at момент, когда заведение открыто, нулевое - не найдено.
+// Открыться оно может только в начале интервала, часов работы или суток.
+func (s *service) nextOpening(at time.Time) time.Time {
+	starts := []int{0}
+
+	for _, interval := range s.musicData.Intervals {
+		starts = append(starts, interval.Start)
+	}
+
+	for _, oh := range s.openingHours() {
+		starts = append(starts, oh.Start)
+	}
+
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+
+	for i := 0; i < openingSearchDays; i++ {
+		candidates := make([]time.Time, 0, len(starts))
+
+		for _, sec := range starts {
+			candidates = append(candidates, wallTime(day, sec))
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Before(candidates[j])
+		})
+
+		for _, c := range candidates {
+			if c.After(at) && s.isOpen(c) {
+				return c
+			}
+		}
+
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}
+}
+
+func (s *service) openingHours() []domain.OpeningHours {
+	s.state.PlayerInfo.RLock()
+	defer s.state.PlayerInfo.RUnlock()
+
+	if playerInfo := s.state.PlayerInfo.Get(); playerInfo != nil {
+		return playerInfo.OpeningHours
+	}
+
+	return nil
+}
+
+// updateVenue публикует, открыто ли заведение, и сообщает об изменении.
+func (s *service) updateVenue(now time.Time) (closed bool, opensAt time.Time) {
+	closed = !s.isOpen(now)
+
+	if closed {
+		opensAt = s.nextOpening(now)
+	}
+
+	s.state.Venue.Lock()
+	changed := s.state.Venue.Closed() != closed || !s.state.Venue.OpensAt().Equal(opensAt)
+	s.state.Venue.Set(closed, opensAt)
+	s.state.Venue.Unlock()
+
+	if changed {
+		s.logger.Info().Bool("closed", closed).Time("opensAt", opensAt).Msg("venue status changed")
+
+		if s.venueCb != nil {
+			s.venueCb(closed, opensAt)
+		}
+	}
+
+	return closed, opensAt
+}
+
+func worksOnWeekday(days []int, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+
+	isoWeekday := int(weekday)
+
+	if weekday == time.Sunday {
+		isoWeekday = 7
+	}
+
+	for _, d := range days {
+		if d == isoWeekday {
+			return true
+		}
+	}
+
+	return false
+}
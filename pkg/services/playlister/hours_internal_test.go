@@ -0,0 +1,106 @@
+package playlister
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func newHoursTestService(now time.Time, hours ...domain.OpeningHours) *service {
+	st := state.NewState()
+	st.PlayerInfo.Set(&domain.PlayerInfo{OpeningHours: hours})
+	st.MusicData.Set(&domain.MusicData{
+		// 08:00 - 23:00
+		Intervals: []*domain.MusicDataInterval{{Start: 8 * 3600, End: 23 * 3600, TrackIDs: []int{1}}},
+		Tracks:    []*domain.Track{{ID: 1, Duration: 3 * time.Minute}},
+	})
+
+	svc := NewService(st, zerolog.Nop(), clockwork.NewFakeClockAt(now), Rules{}, "UTC", nil)
+	svc.musicData = st.MusicData.Get()
+
+	return svc
+}
+
+func Test_isOpen(t *testing.T) {
+	// пн-пт 10:00 - 02:00, сб 12:00 - 18:00
+	hours := []domain.OpeningHours{
+		{Days: []int{1, 2, 3, 4, 5}, Start: 10 * 3600, End: 2 * 3600},
+		{Days: []int{6}, Start: 12 * 3600, End: 18 * 3600},
+	}
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		hours []domain.OpeningHours
+		want  bool
+	}{
+		{name: "no opening hours, within interval", at: time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC), want: true},
+		{name: "no interval", at: time.Date(2021, time.March, 1, 3, 0, 0, 0, time.UTC), want: false},
+		{name: "before opening", at: time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC), hours: hours, want: false},
+		{name: "open", at: time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC), hours: hours, want: true},
+		{name: "closed day", at: time.Date(2021, time.March, 7, 13, 0, 0, 0, time.UTC), hours: hours, want: false},
+		{name: "saturday", at: time.Date(2021, time.March, 6, 13, 0, 0, 0, time.UTC), hours: hours, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newHoursTestService(tt.at, tt.hours...)
+
+			if got := svc.isOpen(tt.at); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("overnight belongs to start day", func(t *testing.T) {
+		// только понедельник 20:00 - 02:00, интервал 08:00 - 04:00
+		svc := newHoursTestService(time.Time{}, domain.OpeningHours{Days: []int{1}, Start: 20 * 3600, End: 2 * 3600})
+		svc.musicData.Intervals[0].End = 4 * 3600
+
+		if !svc.isOpen(time.Date(2021, time.March, 2, 1, 0, 0, 0, time.UTC)) {
+			t.Error("got closed on tuesday 01:00, want open")
+		}
+
+		if svc.isOpen(time.Date(2021, time.March, 1, 1, 0, 0, 0, time.UTC)) {
+			t.Error("got open on monday 01:00, want closed")
+		}
+	})
+}
+
+func Test_updateList_closed(t *testing.T) {
+	// суббота 19:00, по воскресеньям закрыто, в понедельник открытие в 10:00
+	now := time.Date(2021, time.March, 6, 19, 0, 0, 0, time.UTC)
+	svc := newHoursTestService(now,
+		domain.OpeningHours{Days: []int{1, 2, 3, 4, 5, 6}, Start: 10 * 3600, End: 18 * 3600})
+
+	var calls []time.Time
+
+	svc.venueCb = func(closed bool, opensAt time.Time) {
+		if closed {
+			calls = append(calls, opensAt)
+		}
+	}
+
+	svc.updateList(true)
+	svc.updateList(false)
+
+	wantOpensAt := time.Date(2021, time.March, 8, 10, 0, 0, 0, time.UTC)
+
+	if len(calls) != 1 || !calls[0].Equal(wantOpensAt) {
+		t.Fatalf("got venueCb calls %v, want one with %s", calls, wantOpensAt)
+	}
+
+	if !svc.state.Venue.Closed() || !svc.state.Venue.OpensAt().Equal(wantOpensAt) {
+		t.Errorf("got venue closed %v, opensAt %s", svc.state.Venue.Closed(), svc.state.Venue.OpensAt())
+	}
+
+	// плейлист готов к открытию
+	if got := len(svc.state.Playlist.Get()); got != trackCount {
+		t.Errorf("got playlist len %d, want %d", got, trackCount)
+	}
+}
@@ -0,0 +1,242 @@
+package playlister
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const noArtistGap = math.MaxInt32
+
+// candidate - трек интервала и насколько он близок к своим повторам.
+type candidate struct {
+	track      *domain.Track
+	inPlaylist bool
+	// треков до ближайшего трека того же исполнителя в истории или плейлисте
+	artistGap int
+	// когда трек играл последний раз, нулевое - не играл
+	lastPlayedAt time.Time
+}
+
+// pickTrack выбирает трек для места trackIndex плейлиста, которое начнет играть в at.
+// Из треков, подходящих под правила, берется случайный. Если таких нет - лучший по порядку:
+// не из плейлиста, с самым дальним исполнителем, давнее всех игравший, с меньшим ID.
+func (s *service) pickTrack(trackIDs []int, trackIndex int, at time.Time) (*domain.Track, bool) {
+	candidates := s.candidates(trackIDs, trackIndex)
+
+	if s.rules.ShuffleBag {
+		candidates = s.bagCandidates(candidates, trackIndex)
+	}
+
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	suitable := make([]*candidate, 0, len(candidates))
+
+	for _, c := range candidates {
+		if s.satisfiesRules(c, at) {
+			suitable = append(suitable, c)
+		}
+	}
+
+	if len(suitable) > 0 {
+		//nolint:gosec
+		return suitable[rand.Intn(len(suitable))].track, true
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+
+		if a.inPlaylist != b.inPlaylist {
+			return !a.inPlaylist
+		}
+
+		if ga, gb := s.cappedArtistGap(a), s.cappedArtistGap(b); ga != gb {
+			return ga > gb
+		}
+
+		if !a.lastPlayedAt.Equal(b.lastPlayedAt) {
+			return a.lastPlayedAt.Before(b.lastPlayedAt)
+		}
+
+		return a.track.ID < b.track.ID
+	})
+
+	s.logger.Debug().Int("trackIndex", trackIndex).
+		Int("trackId", candidates[0].track.ID).
+		Int("artistGap", candidates[0].artistGap).
+		Time("lastPlayedAt", candidates[0].lastPlayedAt).
+		Msg("separation rules relaxed")
+
+	return candidates[0].track, true
+}
+
+func (s *service) satisfiesRules(c *candidate, at time.Time) bool {
+	if c.inPlaylist {
+		return false
+	}
+
+	if s.rules.ArtistSeparation > 0 && c.artistGap < s.rules.ArtistSeparation {
+		return false
+	}
+
+	if s.rules.TrackRepeat > 0 && !c.lastPlayedAt.IsZero() && at.Sub(c.lastPlayedAt) < s.rules.TrackRepeat {
+		return false
+	}
+
+	return true
+}
+
+func (s *service) cappedArtistGap(c *candidate) int {
+	if s.rules.ArtistSeparation > 0 && c.artistGap > s.rules.ArtistSeparation {
+		return s.rules.ArtistSeparation
+	}
+
+	return c.artistGap
+}
+
+// candidates - доступные треки интервала. Соседи считаются по истории и фоновым трекам
+// плейлиста до и после trackIndex, реклама не учитывается.
+func (s *service) candidates(trackIDs []int, trackIndex int) []*candidate {
+	playlist := s.state.Playlist.Get()
+	inPlaylist := make(map[int]struct{}, len(playlist))
+	lastPlayedAt := make(map[int]time.Time)
+	artistGaps := make(map[int]int)
+
+	// назад от trackIndex: плейлист, затем история
+	gap := 0
+	setGap := func(artistID int) {
+		if _, ok := artistGaps[artistID]; !ok && artistID != 0 {
+			artistGaps[artistID] = gap
+		}
+
+		gap++
+	}
+
+	for i := trackIndex - 1; i >= 0 && i < len(playlist); i-- {
+		if playlist[i].Type != domain.PlaylistTrackTypeAd {
+			setGap(playlist[i].Track.Artist.ID)
+		}
+	}
+
+	for i := len(s.history) - 1; i >= 0; i-- {
+		e := s.history[i]
+
+		setGap(e.ArtistID)
+
+		if _, ok := lastPlayedAt[e.TrackID]; !ok {
+			lastPlayedAt[e.TrackID] = e.PlayedAt
+		}
+	}
+
+	// вперед от trackIndex
+	gap = 0
+
+	for i := trackIndex + 1; i < len(playlist); i++ {
+		if playlist[i].Type == domain.PlaylistTrackTypeAd {
+			continue
+		}
+
+		artistID := playlist[i].Track.Artist.ID
+
+		if g, ok := artistGaps[artistID]; artistID != 0 && (!ok || gap < g) {
+			artistGaps[artistID] = gap
+		}
+
+		gap++
+	}
+
+	for i, pt := range playlist {
+		if i != trackIndex {
+			inPlaylist[pt.Track.ID] = struct{}{}
+		}
+	}
+
+	tracks := make(map[int]*domain.Track, len(trackIDs))
+
+	for _, trackID := range trackIDs {
+		tracks[trackID] = nil
+	}
+
+	for _, t := range s.musicData.Tracks {
+		if _, ok := tracks[t.ID]; ok {
+			tracks[t.ID] = t
+		}
+	}
+
+	candidates := make([]*candidate, 0, len(trackIDs))
+
+	for _, trackID := range trackIDs {
+		t := tracks[trackID]
+
+		if t == nil || s.isUnavailable(trackID) {
+			continue
+		}
+
+		// один трек в интервале может встретиться дважды
+		tracks[trackID] = nil
+
+		c := &candidate{track: t, artistGap: noArtistGap, lastPlayedAt: lastPlayedAt[trackID]}
+
+		if g, ok := artistGaps[t.Artist.ID]; ok {
+			c.artistGap = g
+		}
+
+		_, c.inPlaylist = inPlaylist[trackID]
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// bagCandidates оставляет треки, еще не сыгранные в текущем круге мешка и не из плейлиста. Круги считаются
+// по истории и плейлисту: когда сыграны все доступные треки интервала, начинается новый.
+func (s *service) bagCandidates(candidates []*candidate, trackIndex int) []*candidate {
+	bag := make(map[int]struct{}, len(candidates))
+
+	for _, c := range candidates {
+		bag[c.track.ID] = struct{}{}
+	}
+
+	played := make(map[int]struct{}, len(bag))
+	take := func(trackID int) {
+		if _, ok := bag[trackID]; !ok {
+			return
+		}
+
+		played[trackID] = struct{}{}
+
+		if len(played) == len(bag) {
+			played = make(map[int]struct{}, len(bag))
+		}
+	}
+
+	for _, e := range s.history {
+		take(e.TrackID)
+	}
+
+	for i, pt := range s.state.Playlist.Get() {
+		if i != trackIndex && pt.Type != domain.PlaylistTrackTypeAd {
+			take(pt.Track.ID)
+		}
+	}
+
+	remaining := make([]*candidate, 0, len(candidates)-len(played))
+
+	for _, c := range candidates {
+		if _, ok := played[c.track.ID]; !ok && !c.inPlaylist {
+			remaining = append(remaining, c)
+		}
+	}
+
+	// круг сменился внутри плейлиста, а остаток уже в нем
+	if len(remaining) == 0 {
+		return candidates
+	}
+
+	return remaining
+}
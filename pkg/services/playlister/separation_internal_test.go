@@ -0,0 +1,102 @@
+package playlister
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+// newSeparationTestService - треки 1..len(artistIDs) с заданными исполнителями в одном интервале.
+func newSeparationTestService(rules Rules, artistIDs ...int) *service {
+	tracks := make([]*domain.Track, 0, len(artistIDs))
+	trackIDs := make([]int, 0, len(artistIDs))
+
+	for i, artistID := range artistIDs {
+		tracks = append(tracks, &domain.Track{ID: i + 1, Artist: domain.Artist{ID: artistID}, Duration: 3 * time.Minute})
+		trackIDs = append(trackIDs, i+1)
+	}
+
+	st := state.NewState()
+	st.MusicData.Set(&domain.MusicData{
+		Intervals: []*domain.MusicDataInterval{{Start: 0, End: secondsInDay, TrackIDs: trackIDs}},
+		Tracks:    tracks,
+	})
+
+	svc := NewService(st, zerolog.Nop(), clockwork.NewFakeClockAt(adsTestNow), rules, "UTC", nil)
+	svc.musicData = st.MusicData.Get()
+
+	return svc
+}
+
+func Test_updateList_artistSeparation(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		svc := newSeparationTestService(Rules{ArtistSeparation: 2}, 1, 1, 2, 2, 3, 3)
+		svc.state.History.Add(&domain.HistoryEntry{TrackID: 1, ArtistID: 1, PlayedAt: adsTestNow})
+		svc.updateList(true)
+
+		artists := []int{1}
+
+		for _, pt := range svc.state.Playlist.Get() {
+			artists = append(artists, pt.Track.Artist.ID)
+		}
+
+		for i := 1; i < len(artists); i++ {
+			if artists[i] == artists[i-1] || i > 1 && artists[i] == artists[i-2] {
+				t.Fatalf("artists too close: %v", artists)
+			}
+		}
+	}
+}
+
+func Test_updateList_separationFallback(t *testing.T) {
+	svc := newSeparationTestService(Rules{ArtistSeparation: 2, TrackRepeat: time.Hour}, 1, 1, 1)
+	svc.trackCount = 1
+	svc.state.History.Add(&domain.HistoryEntry{TrackID: 2, ArtistID: 1, PlayedAt: adsTestNow.Add(-30 * time.Minute)})
+	svc.state.History.Add(&domain.HistoryEntry{TrackID: 1, ArtistID: 1, PlayedAt: adsTestNow.Add(-10 * time.Minute)})
+
+	// правила не выполнить - берется никогда не игравший трек
+	svc.updateList(true)
+
+	if got := svc.state.Playlist.Get()[0].Track.ID; got != 3 {
+		t.Errorf("got track %d, want 3", got)
+	}
+
+	// затем - давнее всех игравший
+	svc.state.History.Add(&domain.HistoryEntry{TrackID: 3, ArtistID: 1, PlayedAt: adsTestNow.Add(-5 * time.Minute)})
+	svc.updateList(true)
+
+	if got := svc.state.Playlist.Get()[0].Track.ID; got != 2 {
+		t.Errorf("got track %d, want 2", got)
+	}
+}
+
+func Test_updateList_shuffleBag(t *testing.T) {
+	svc := newSeparationTestService(Rules{ShuffleBag: true}, 1, 2, 3, 4, 5, 6, 7)
+	svc.trackCount = 1
+
+	// два полных круга: каждый трек по разу в каждом
+	for round := 0; round < 2; round++ {
+		seen := make(map[int]struct{})
+
+		for i := 0; i < 7; i++ {
+			svc.updateList(true)
+
+			pt := svc.state.Playlist.Get()[0]
+
+			if _, ok := seen[pt.Track.ID]; ok {
+				t.Fatalf("round %d: track %d repeated before bag emptied", round, pt.Track.ID)
+			}
+
+			seen[pt.Track.ID] = struct{}{}
+
+			// трек сыгран
+			svc.state.Playlist.Shift()
+			svc.state.History.Add(&domain.HistoryEntry{TrackID: pt.Track.ID, ArtistID: pt.Track.Artist.ID, PlayedAt: adsTestNow})
+		}
+	}
+}
@@ -2,13 +2,13 @@ package playlister
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/rs/zerolog"
-	"golang.org/x/tools/go/ssa/interp/testdata/src/errors"
 
 	"github.com/qkveri/player_core/pkg/domain"
 	"github.com/qkveri/player_core/pkg/state"
@@ -22,21 +22,57 @@ const (
 	secondsInDay = 86400
 )
 
+// Rules - правила составления плейлиста, нулевые и отрицательные значения отключают правило.
+type Rules struct {
+	// реклама, опоздавшая больше чем на AdMaxDelay, не играет
+	AdMaxDelay time.Duration
+	// минимум треков между треками одного исполнителя
+	ArtistSeparation int
+	// минимум времени до повтора трека
+	TrackRepeat time.Duration
+	// каждый трек интервала играет по разу, прежде чем какой-то повторится
+	ShuffleBag bool
+}
+
 type service struct {
 	musicData *domain.MusicData
+	// запланированные и пропущенные выходы рекламы (ключ adSlot.key())
+	adSlots map[string]time.Time
+	// треки, которые не удается скачать, и история на момент обновления плейлиста
+	unavailable map[int]struct{}
+	history     []*domain.HistoryEntry
+	// часовой пояс заведения и его имя
+	loc     *time.Location
+	locName string
 
 	state      *state.State
 	logger     zerolog.Logger
 	clock      clockwork.Clock
 	trackCount int
+	rules      Rules
+	// IANA-пояс, если его нет в PlayerInfo, пустой - пояс устройства
+	defaultTimezone string
+	// вызывается, когда заведение закрылось или открылось
+	venueCb func(closed bool, opensAt time.Time)
 }
 
-func NewService(state *state.State, logger zerolog.Logger, clock clockwork.Clock) *service {
+func NewService(
+	state *state.State,
+	logger zerolog.Logger,
+	clock clockwork.Clock,
+	rules Rules,
+	defaultTimezone string,
+	venueCb func(closed bool, opensAt time.Time),
+) *service {
 	return &service{
-		state:      state,
-		logger:     logger.With().Str("service", "playlister").Logger(),
-		clock:      clock,
-		trackCount: trackCount,
+		state:           state,
+		logger:          logger.With().Str("service", "playlister").Logger(),
+		clock:           clock,
+		trackCount:      trackCount,
+		rules:           rules,
+		defaultTimezone: defaultTimezone,
+		venueCb:         venueCb,
+		adSlots:         make(map[string]time.Time),
 	}
 }
 
@@ -80,29 +116,97 @@ func (s *service) updateList(force bool) {
 		return
 	}
 
+	now := s.clock.Now().In(s.location())
+
+	// плейлист начинается после текущего трека плеера
+	s.state.Player.RLock()
+	at := now.Add(s.state.Player.Remaining())
+	s.state.Player.RUnlock()
+
+	s.updateVolume(now)
+
+	// закрытое заведение молчит, плейлист готовится к открытию
+	if closed, opensAt := s.updateVenue(now); closed && !opensAt.IsZero() {
+		at = opensAt
+	}
+
 	// lock playlist...
 	s.state.Playlist.Lock()
 	defer s.state.Playlist.Unlock()
 
-	now := s.clock.Now()
-	seconds := now.Hour()*3600 + now.Minute()*60 + now.Second()
-	addSeconds := func(d time.Duration) { seconds = (seconds + int(d.Seconds())) % secondsInDay }
 	playlist := s.state.Playlist.Get()
 	updated := 0
 
+	s.pruneAdSlots(now)
+
+	s.state.Tracks.RLock()
+	s.unavailable = s.state.Tracks.Unavailable()
+	s.state.Tracks.RUnlock()
+
+	s.state.History.RLock()
+	s.history = s.state.History.Entries()
+	s.state.History.RUnlock()
+
+	if force {
+		s.forgetPlannedAds(playlist)
+	}
+
 	for i := 0; i < s.trackCount; i++ {
 		trackIndex := i
-		intervalIndex := s.intervalIndexBySeconds(seconds)
+		playlist = s.state.Playlist.Get()
+
+		// уже запланированная реклама остается на своем месте, недоступная заменяется фоновым треком
+		if !force && len(playlist) > trackIndex && playlist[trackIndex].Type == domain.PlaylistTrackTypeAd &&
+			!s.isUnavailable(playlist[trackIndex].Track.ID) {
+			at = at.Add(playlist[trackIndex].Track.Duration)
+			continue
+		}
+
+		if slot, ok := s.dueAdSlot(at); ok {
+			s.adSlots[slot.key()] = slot.at
+
+			if len(playlist) > trackIndex {
+				if key, isAd := playlistTrackAdSlotKey(playlist[trackIndex]); isAd && key == slot.key() {
+					at = at.Add(playlist[trackIndex].Track.Duration)
+					continue
+				}
+			}
+
+			adTrack := slot.playlistTrack()
+
+			switch {
+			case len(playlist) <= trackIndex:
+				s.state.Playlist.Append(adTrack)
+			case force:
+				s.state.Playlist.Replace(adTrack, trackIndex)
+			default:
+				s.state.Playlist.Insert(adTrack, trackIndex)
+			}
+
+			s.logger.Debug().Int("adId", slot.ad.ID).
+				Time("scheduledAt", slot.at).
+				Time("at", at).
+				Int("trackIndex", trackIndex).
+				Msg("ad slot planned")
+
+			at = at.Add(adTrack.Track.Duration)
+			updated++
+
+			continue
+		}
+
+		intervalIndex := s.intervalIndexBySeconds(secondsOfDay(at))
 
 		if !force {
 			// если трек на нужном месте - пропускам
-			if len(playlist) > trackIndex && playlist[trackIndex].BackgroundIntervalIndex == intervalIndex {
-				addSeconds(playlist[trackIndex].Track.Duration)
+			if len(playlist) > trackIndex && playlist[trackIndex].BackgroundIntervalIndex == intervalIndex &&
+				!s.isUnavailable(playlist[trackIndex].Track.ID) {
+				at = at.Add(playlist[trackIndex].Track.Duration)
 				continue
 			}
 		}
 
-		newTrack, err := s.getPlaylistTrackByIntervalIndex(intervalIndex)
+		newTrack, err := s.getPlaylistTrackByIntervalIndex(intervalIndex, trackIndex, at)
 
 		if err != nil {
 			s.logger.Err(err).
@@ -119,29 +223,50 @@ func (s *service) updateList(force bool) {
 			s.state.Playlist.Append(newTrack)
 		}
 
-		addSeconds(newTrack.Track.Duration)
+		at = at.Add(newTrack.Track.Duration)
 
 		updated++
 	}
 
-	s.logger.Debug().Int("updated", updated).Msg("updated playlist")
+	// реклама сдвигает фоновые треки, лишние убираем
+	removed := s.state.Playlist.Truncate(s.trackCount)
+	s.forgetPlannedAds(removed)
+
+	s.logger.Debug().Int("updated", updated).Int("removed", len(removed)).Msg("updated playlist")
+}
+
+func (s *service) isUnavailable(trackID int) bool {
+	_, ok := s.unavailable[trackID]
+
+	return ok
+}
+
+func secondsOfDay(t time.Time) int {
+	return t.Hour()*3600 + t.Minute()*60 + t.Second()
 }
 
 func (s *service) intervalIndexBySeconds(seconds int) int {
+	index, _ := s.findIntervalIndex(seconds)
+
+	return index
+}
+
+// findIntervalIndex - интервал, покрывающий время, false - такого нет.
+func (s *service) findIntervalIndex(seconds int) (int, bool) {
 	for index, interval := range s.musicData.Intervals {
 		// переходящий интервал (со дня в другой день)
 		if interval.Start > interval.End {
-			if interval.Start <= seconds && seconds < 86400 || 0 <= seconds && seconds < interval.End {
-				return index
+			if interval.Start <= seconds && seconds < secondsInDay || 0 <= seconds && seconds < interval.End {
+				return index, true
 			}
 		}
 
 		if interval.Start <= seconds && seconds < interval.End {
-			return index
+			return index, true
 		}
 	}
 
-	return 0
+	return 0, false
 }
 
 var intervalsEmpty = errors.New("musicData.Interval not exists")
@@ -175,44 +300,17 @@ func (s *service) getTrackIdByIntervalIndex(index int) (int, error) {
 	return randTrackID, nil
 }
 
-func (s *service) getPlaylistTrackByIntervalIndex(index int) (*domain.PlaylistTrack, error) {
+// getPlaylistTrackByIntervalIndex подбирает трек интервала для места trackIndex, которое начнет играть в at.
+func (s *service) getPlaylistTrackByIntervalIndex(index, trackIndex int, at time.Time) (*domain.PlaylistTrack, error) {
 	if index >= len(s.musicData.Intervals) {
 		return nil, errors.New("musicData.Interval not exists")
 	}
 
-	playlist := s.state.Playlist.Get()
-	currentTrackIDs := make(map[int]struct{}, len(playlist))
-
-	for _, t := range playlist {
-		currentTrackIDs[t.Track.ID] = struct{}{}
-	}
-
-	// Проверяем есть ли трек в списке.
-	// Треки берутся рандомно, если добавляемый трек есть в списке, то мы рекурсивно пробуем другой.
-	var randTrackID int
-
-	for ai := 0; ai < numberSelectionAttempts; ai++ {
-		//nolint:gosec
-		randTrackIndex := rand.Intn(len(s.musicData.Intervals[index].TrackIDs)-0) + 0
-		randTrackID = s.musicData.Intervals[index].TrackIDs[randTrackIndex]
-
-		if _, has := currentTrackIDs[randTrackID]; !has {
-			break
-		}
-	}
-
-	var track *domain.Track
-
-	for _, t := range s.musicData.Tracks {
-		if t.ID == randTrackID {
-			track = t
-			break
-		}
-	}
+	track, ok := s.pickTrack(s.musicData.Intervals[index].TrackIDs, trackIndex, at)
 
-	if track == nil {
+	if !ok {
 		//nolint:goerr113
-		return nil, fmt.Errorf("musicData.Tracks not exists track with ID %d", randTrackID)
+		return nil, fmt.Errorf("no available tracks in interval %d", index)
 	}
 
 	return &domain.PlaylistTrack{
@@ -54,3 +54,29 @@ func Test_getTrackIdByIntervalIndex(t *testing.T) {
 		}
 	})
 }
+
+func Test_updateList_unavailable(t *testing.T) {
+	svc, _ := newAdsTestService(adsTestNow)
+	svc.updateList(true)
+
+	replaced := svc.state.Playlist.Get()[2]
+
+	svc.state.Tracks.SetUnavailable(replaced.Track.ID)
+	svc.updateList(false)
+
+	items := svc.state.Playlist.Get()
+
+	if len(items) != trackCount {
+		t.Fatalf("got len %d, want %d", len(items), trackCount)
+	}
+
+	for i, pt := range items {
+		if pt.Track.ID == replaced.Track.ID {
+			t.Errorf("unavailable track %d still at %d", pt.Track.ID, i)
+		}
+	}
+
+	if items[2].BackgroundIntervalIndex != replaced.BackgroundIntervalIndex {
+		t.Errorf("got interval %d, want %d", items[2].BackgroundIntervalIndex, replaced.BackgroundIntervalIndex)
+	}
+}
@@ -0,0 +1,74 @@
+package playlister
+
+import (
+	"time"
+)
+
+// location - часовой пояс заведения: из PlayerInfo, иначе из настроек, иначе устройства.
+// Интервалы и расписание рекламы считаются по местному времени этого пояса.
+func (s *service) location() *time.Location {
+	var name string
+
+	s.state.PlayerInfo.RLock()
+	if playerInfo := s.state.PlayerInfo.Get(); playerInfo != nil {
+		name = playerInfo.Timezone
+	}
+	s.state.PlayerInfo.RUnlock()
+
+	if s.loc != nil && name == s.locName {
+		return s.loc
+	}
+
+	loc := time.Local
+
+	for _, tz := range []string{name, s.defaultTimezone} {
+		if tz == "" {
+			continue
+		}
+
+		l, err := time.LoadLocation(tz)
+
+		if err != nil {
+			s.logger.Warn().Err(err).Str("timezone", tz).Msg("cannot load timezone")
+			continue
+		}
+
+		loc = l
+
+		break
+	}
+
+	s.logger.Info().Str("timezone", name).Str("location", loc.String()).Msg("venue timezone")
+
+	s.loc, s.locName = loc, name
+
+	return loc
+}
+
+// wallTime - момент, когда на часах дня day (в его поясе) будет sec секунд от начала суток.
+// При переводе часов вперед несуществующее время сдвигается на величину перевода
+// (02:30 -> 03:30), при переводе назад повторяющееся время берется в первый раз.
+func wallTime(day time.Time, sec int) time.Time {
+	y, m, d := day.Date()
+	loc := day.Location()
+
+	// смещение пояса в начале суток - до перевода часов
+	_, offset := time.Date(y, m, d, 0, 0, 0, 0, loc).Zone()
+	beforeShift := time.Date(y, m, d, 0, 0, sec, 0, time.FixedZone("", offset)).In(loc)
+	at := time.Date(y, m, d, 0, 0, sec, 0, loc)
+
+	matches := func(t time.Time) bool {
+		ty, tm, td := t.Date()
+
+		return ty == y && tm == m && td == d && secondsOfDay(t) == sec
+	}
+
+	switch {
+	case matches(beforeShift) && (!matches(at) || beforeShift.Before(at)):
+		return beforeShift
+	case matches(at):
+		return at
+	default:
+		return beforeShift
+	}
+}
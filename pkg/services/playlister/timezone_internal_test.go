@@ -0,0 +1,94 @@
+package playlister
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+func Test_wallTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spring := time.Date(2021, time.March, 14, 0, 0, 0, 0, loc)
+	fall := time.Date(2021, time.November, 7, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name string
+		day  time.Time
+		sec  int
+		want time.Time
+	}{
+		{name: "before spring forward", day: spring, sec: 3600, want: time.Date(2021, time.March, 14, 6, 0, 0, 0, time.UTC)},
+		{name: "skipped by spring forward", day: spring, sec: 2*3600 + 1800, want: time.Date(2021, time.March, 14, 7, 30, 0, 0, time.UTC)},
+		{name: "after spring forward", day: spring, sec: 12 * 3600, want: time.Date(2021, time.March, 14, 16, 0, 0, 0, time.UTC)},
+		{name: "repeated by fall back", day: fall, sec: 3600 + 1800, want: time.Date(2021, time.November, 7, 5, 30, 0, 0, time.UTC)},
+		{name: "after fall back", day: fall, sec: 12 * 3600, want: time.Date(2021, time.November, 7, 17, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wallTime(tt.day, tt.sec); !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateList_timezone(t *testing.T) {
+	newService := func(timezone string) *service {
+		st := state.NewState()
+		st.PlayerInfo.Set(&domain.PlayerInfo{Timezone: timezone})
+		st.MusicData.Set(&domain.MusicData{
+			Intervals: []*domain.MusicDataInterval{
+				{Start: 0, End: 10 * 3600, TrackIDs: []int{1}},
+				{Start: 10 * 3600, End: secondsInDay, TrackIDs: []int{2}},
+			},
+			Tracks: []*domain.Track{{ID: 1, Duration: time.Minute}, {ID: 2, Duration: time.Minute}},
+		})
+
+		// 01:00 UTC - 11:00 во Владивостоке
+		clock := clockwork.NewFakeClockAt(time.Date(2021, time.March, 1, 1, 0, 0, 0, time.UTC))
+		svc := NewService(st, zerolog.Nop(), clock, Rules{}, "UTC", nil)
+		svc.musicData = st.MusicData.Get()
+		svc.trackCount = 1
+
+		return svc
+	}
+
+	for timezone, want := range map[string]int{"": 1, "Asia/Vladivostok": 2, "Invalid/Zone": 1} {
+		svc := newService(timezone)
+		svc.updateList(true)
+
+		if got := svc.state.Playlist.Get()[0].Track.ID; got != want {
+			t.Errorf("timezone %q: got track %d, want %d", timezone, got, want)
+		}
+	}
+}
+
+func Test_updateList_adsDST(t *testing.T) {
+	noon := 12 * 3600
+
+	// 11:58 по Нью-Йорку в день перевода часов вперед
+	svc, _ := newAdsTestService(time.Date(2021, time.March, 14, 15, 58, 0, 0, time.UTC), newTestAd(1, nil, noon))
+	svc.state.PlayerInfo.Set(&domain.PlayerInfo{Timezone: "America/New_York"})
+	svc.updateList(true)
+
+	items := svc.state.Playlist.Get()
+
+	if got := adPositions(items); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("got ad positions %v, want [1]", got)
+	}
+
+	if want := time.Date(2021, time.March, 14, 16, 0, 0, 0, time.UTC); !items[1].AdScheduledAt.Equal(want) {
+		t.Errorf("got scheduledAt %s, want %s", items[1].AdScheduledAt.UTC(), want)
+	}
+}
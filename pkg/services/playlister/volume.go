@@ -0,0 +1,44 @@
+package playlister
+
+import (
+	"time"
+)
+
+const (
+	volumeMin = 0
+	volumeMax = 1
+)
+
+// updateVolume публикует громкость интервала, который идет сейчас, плеер плавно переходит на нее.
+func (s *service) updateVolume(now time.Time) {
+	volume := s.scheduledVolume(now)
+
+	s.state.Venue.Lock()
+	changed := s.state.Venue.Volume() != volume
+	s.state.Venue.SetVolume(volume)
+	s.state.Venue.Unlock()
+
+	if changed {
+		s.logger.Info().Float64("volume", volume).Msg("scheduled volume changed")
+	}
+}
+
+// scheduledVolume - громкость интервала в at, без интервала или громкости - полная.
+func (s *service) scheduledVolume(at time.Time) float64 {
+	index, ok := s.findIntervalIndex(secondsOfDay(at))
+
+	if !ok || s.musicData.Intervals[index].Volume == nil {
+		return volumeMax
+	}
+
+	volume := *s.musicData.Intervals[index].Volume
+
+	switch {
+	case volume < volumeMin:
+		return volumeMin
+	case volume > volumeMax:
+		return volumeMax
+	default:
+		return volume
+	}
+}
@@ -0,0 +1,40 @@
+package playlister
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+func Test_scheduledVolume(t *testing.T) {
+	quiet, loud := 0.4, 1.5
+
+	svc := newHoursTestService(time.Time{})
+	svc.musicData.Intervals = []*domain.MusicDataInterval{
+		{Start: 8 * 3600, End: 12 * 3600},
+		{Start: 12 * 3600, End: 15 * 3600, Volume: &quiet},
+		{Start: 15 * 3600, End: 23 * 3600, Volume: &loud},
+	}
+
+	tests := []struct {
+		name string
+		hour int
+		want float64
+	}{
+		{name: "no volume", hour: 9, want: volumeMax},
+		{name: "quiet", hour: 13, want: quiet},
+		{name: "clamped", hour: 20, want: volumeMax},
+		{name: "no interval", hour: 3, want: volumeMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := time.Date(2021, time.March, 1, tt.hour, 0, 0, 0, time.UTC)
+
+			if got := svc.scheduledVolume(at); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package playlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/services/player"
+	"github.com/qkveri/player_core/pkg/utils"
+)
+
+const (
+	uploadInterval = 30 * time.Second
+	batchSize      = 100
+	// сверх этого старые записи отбрасываются
+	queueMaxLen = 20000
+
+	retryMin = 10 * time.Second
+	retryMax = 10 * time.Minute
+)
+
+// service копит события проигрывания в очереди на устройстве и пачками отправляет их на сервер.
+// У каждой записи свой ID, поэтому повторная отправка после обрыва не задваивает выходы.
+type service struct {
+	mu    sync.Mutex
+	queue []*domain.PlayLogEntry
+	dirty chan struct{}
+	// fm не дает сохранению записать очередь поверх Clear
+	fm sync.Mutex
+
+	repo   domain.PlayLogRepository
+	sender domain.PlayLogSender
+	logger zerolog.Logger
+}
+
+func NewService(repo domain.PlayLogRepository, sender domain.PlayLogSender, logger zerolog.Logger) *service {
+	return &service{
+		dirty:  make(chan struct{}, 1),
+		repo:   repo,
+		sender: sender,
+		logger: logger.With().Str("service", "playlog").Logger(),
+	}
+}
+
+// Load восстанавливает неотправленные записи, вызывать до Run.
+func (s *service) Load(ctx context.Context) {
+	entries, err := s.repo.Get(ctx)
+
+	if err != nil {
+		s.logger.Err(err).Msg("play log load fail")
+		return
+	}
+
+	s.mu.Lock()
+	s.queue = append(entries, s.queue...)
+	s.mu.Unlock()
+
+	s.logger.Debug().Int("entries", len(entries)).Msg("play log loaded")
+}
+
+func (s *service) TrackStarted(item *domain.PlaylistTrack, at time.Time) {
+	s.add(newEntry(item, domain.PlayLogEventStart, at))
+}
+
+func (s *service) TrackFinished(item *domain.PlaylistTrack, finish player.Finish) {
+	event := domain.PlayLogEventFinish
+
+	if finish.Skipped {
+		event = domain.PlayLogEventSkip
+	}
+
+	entry := newEntry(item, event, finish.At)
+	entry.PlayedFor = finish.PlayedFor
+	entry.Interrupted = finish.Interrupted
+
+	s.add(entry)
+}
+
+// Run сохраняет очередь на устройстве после каждого изменения. Не зависит от авторизации:
+// без нее плеер может играть дальше, и эти выходы тоже должны дождаться отправки.
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	for {
+		select {
+		case <-ctx.Done():
+			// контекст отменен, сохраняем с новым
+			s.save(context.Background())
+
+			return ctx.Err()
+
+		case <-s.dirty:
+			s.save(ctx)
+		}
+	}
+}
+
+// RunUploader отправляет очередь на сервер, запускать только при авторизации.
+func (s *service) RunUploader(ctx context.Context) error {
+	s.logger.Debug().Msg("uploader starts up")
+	defer s.logger.Debug().Msg("uploader stopped")
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	attempts := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-t.C:
+			delay := uploadInterval
+
+			if err := s.upload(ctx); err != nil {
+				attempts++
+				delay = utils.Backoff(attempts, retryMin, retryMax)
+
+				s.logger.Warn().Err(err).Int("attempts", attempts).Dur("retryIn", delay).Msg("play log upload fail")
+			} else {
+				attempts = 0
+			}
+
+			t.Reset(delay)
+		}
+	}
+}
+
+// Flush отправляет всю очередь сразу, например перед выходом.
+func (s *service) Flush(ctx context.Context) error {
+	return s.upload(ctx)
+}
+
+// Clear забывает неотправленные записи вместе с сохраненными на устройстве.
+func (s *service) Clear(ctx context.Context) error {
+	s.fm.Lock()
+	defer s.fm.Unlock()
+
+	s.mu.Lock()
+	dropped := len(s.queue)
+	s.queue = nil
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		s.logger.Warn().Int("dropped", dropped).Msg("unsent play log cleared")
+	}
+
+	if err := s.repo.Delete(ctx); err != nil {
+		return fmt.Errorf("cannot delete play log: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) add(entry *domain.PlayLogEntry) {
+	s.mu.Lock()
+	s.queue = append(s.queue, entry)
+
+	if dropped := len(s.queue) - queueMaxLen; dropped > 0 {
+		s.logger.Warn().Int("dropped", dropped).Msg("play log queue overflow")
+
+		s.queue = append([]*domain.PlayLogEntry(nil), s.queue[dropped:]...)
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// upload отправляет очередь пачками по batchSize, отправленные записи убираются из очереди.
+func (s *service) upload(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		batch := s.queue
+
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+
+		batch = append([]*domain.PlayLogEntry(nil), batch...)
+		s.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := s.sender.Send(ctx, batch)
+
+		var validationErr *api.ValidationError
+
+		switch {
+		// сервер не примет эту пачку никогда, повтор только остановит очередь
+		case errors.As(err, &validationErr):
+			s.logger.Err(err).Int("entries", len(batch)).Msg("play log batch rejected, dropped")
+
+		case err != nil:
+			return fmt.Errorf("cannot send play log: %w, entries: %d", err, len(batch))
+
+		default:
+			s.logger.Debug().Int("entries", len(batch)).Msg("play log uploaded")
+		}
+
+		s.remove(batch)
+		s.save(ctx)
+	}
+}
+
+// remove убирает записи по ID: пока шла отправка, очередь могла сдвинуться.
+func (s *service) remove(entries []*domain.PlayLogEntry) {
+	ids := make(map[string]struct{}, len(entries))
+
+	for _, e := range entries {
+		ids[e.ID] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := make([]*domain.PlayLogEntry, 0, len(s.queue))
+
+	for _, e := range s.queue {
+		if _, ok := ids[e.ID]; !ok {
+			queue = append(queue, e)
+		}
+	}
+
+	s.queue = queue
+}
+
+func (s *service) save(ctx context.Context) {
+	s.fm.Lock()
+	defer s.fm.Unlock()
+
+	s.mu.Lock()
+	queue := append([]*domain.PlayLogEntry(nil), s.queue...)
+	s.mu.Unlock()
+
+	if err := s.repo.Set(ctx, queue); err != nil {
+		s.logger.Err(err).Msg("play log save fail")
+	}
+}
+
+func newEntry(item *domain.PlaylistTrack, event domain.PlayLogEvent, at time.Time) *domain.PlayLogEntry {
+	entry := &domain.PlayLogEntry{
+		ID:      newEntryID(),
+		Event:   event,
+		At:      at,
+		TrackID: item.Track.ID,
+		Type:    item.Type,
+	}
+
+	if item.Type == domain.PlaylistTrackTypeAd && item.Ad != nil {
+		entry.AdID = item.Ad.ID
+		entry.AdScheduledAt = item.AdScheduledAt
+	}
+
+	return entry
+}
+
+func newEntryID() string {
+	b := make([]byte, 16)
+
+	// crypto/rand на поддерживаемых платформах не возвращает ошибок
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,222 @@
+package playlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/domain/repositories"
+	"github.com/qkveri/player_core/pkg/services/player"
+)
+
+type memoryRepo struct {
+	mu      sync.Mutex
+	entries []*domain.PlayLogEntry
+}
+
+func (m *memoryRepo) Get(context.Context) ([]*domain.PlayLogEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.entries, nil
+}
+
+func (m *memoryRepo) Set(_ context.Context, entries []*domain.PlayLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = entries
+
+	return nil
+}
+
+func (m *memoryRepo) Delete(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = nil
+
+	return nil
+}
+
+func (m *memoryRepo) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.entries)
+}
+
+// fakeBackend - сервер отчетов, отбрасывающий повторы по ID.
+type fakeBackend struct {
+	mu sync.Mutex
+	// ответить ошибкой столько раз, уже записав пачку (ответ потерян)
+	failAfterStore int
+	requests       int
+	received       int
+	events         map[string]string
+}
+
+func (f *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/return_204" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.URL.Path != "/player/play-log" || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Entries []struct {
+			ID    string `json:"id"`
+			Event string `json:"event"`
+		} `json:"entries"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+
+	for _, e := range body.Entries {
+		f.received++
+		f.events[e.ID] = e.Event
+	}
+
+	if f.failAfterStore > 0 {
+		f.failAfterStore--
+		w.WriteHeader(http.StatusBadGateway)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"data":null}`))
+}
+
+func newTestService(backend *fakeBackend) (*service, *memoryRepo, func()) {
+	srv := httptest.NewServer(backend)
+	repo := &memoryRepo{}
+	svc := NewService(repo, repositories.NewPlayLogApiRepo(api.NewHTTPClient(srv.URL)), zerolog.Nop())
+
+	return svc, repo, srv.Close
+}
+
+func Test_upload(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	ad := &domain.PlaylistTrack{
+		Track: &domain.Track{ID: 2, Duration: 30 * time.Second},
+		Type:  domain.PlaylistTrackTypeAd,
+		Ad:    &domain.Ad{ID: 7},
+	}
+	background := &domain.PlaylistTrack{
+		Track: &domain.Track{ID: 1, Duration: time.Minute},
+		Type:  domain.PlaylistTrackTypeBackground,
+	}
+
+	t.Run("retry after lost response does not double count", func(t *testing.T) {
+		backend := &fakeBackend{failAfterStore: 1, events: make(map[string]string)}
+		svc, repo, closeSrv := newTestService(backend)
+		defer closeSrv()
+
+		svc.TrackStarted(ad, at)
+		svc.TrackFinished(ad, player.Finish{At: at.Add(30 * time.Second), PlayedFor: 30 * time.Second})
+		svc.TrackStarted(background, at.Add(30*time.Second))
+		svc.TrackFinished(background, player.Finish{
+			At: at.Add(40 * time.Second), PlayedFor: 10 * time.Second, Skipped: true, Interrupted: true,
+		})
+
+		if err := svc.upload(ctx); err == nil {
+			t.Fatal("got nil error, want upload fail")
+		}
+
+		if len(svc.queue) != 4 {
+			t.Fatalf("got queue len %d after fail, want 4", len(svc.queue))
+		}
+
+		if err := svc.upload(ctx); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if backend.received != 8 || len(backend.events) != 4 {
+			t.Errorf("got received %d, unique %d, want 8 and 4", backend.received, len(backend.events))
+		}
+
+		if len(svc.queue) != 0 || len(repo.entries) != 0 {
+			t.Errorf("got queue len %d, saved %d, want empty", len(svc.queue), len(repo.entries))
+		}
+
+		counts := make(map[string]int)
+
+		for _, event := range backend.events {
+			counts[event]++
+		}
+
+		if counts["start"] != 2 || counts["finish"] != 1 || counts["skip"] != 1 {
+			t.Errorf("got events %v", counts)
+		}
+	})
+
+	t.Run("batches and restored queue", func(t *testing.T) {
+		backend := &fakeBackend{events: make(map[string]string)}
+		svc, repo, closeSrv := newTestService(backend)
+		defer closeSrv()
+
+		for i := 0; i < batchSize+1; i++ {
+			repo.entries = append(repo.entries, newEntry(background, domain.PlayLogEventStart, at))
+		}
+
+		svc.Load(ctx)
+
+		if err := svc.upload(ctx); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if backend.requests != 2 || len(backend.events) != batchSize+1 {
+			t.Errorf("got requests %d, unique %d, want 2 and %d", backend.requests, len(backend.events), batchSize+1)
+		}
+	})
+}
+
+func Test_Run_savesWithoutUploader(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := NewService(repo, nil, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		_ = svc.Run(ctx)
+		close(done)
+	}()
+
+	svc.TrackStarted(&domain.PlaylistTrack{Track: &domain.Track{ID: 1}}, time.Now())
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for repo.len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if repo.len() != 1 {
+		t.Errorf("got saved %d, want 1", repo.len())
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,202 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/utils"
+)
+
+const (
+	minPollInterval = time.Second
+
+	retryMin = 5 * time.Second
+	retryMax = 5 * time.Minute
+
+	// сколько результатов помнить для повторно доставленных команд
+	doneMaxLen = 100
+)
+
+// Controller выполняет команды, ошибка уходит на сервер в подтверждении.
+type Controller interface {
+	Skip()
+	Pause()
+	Resume()
+	SetVolume(volume float64)
+	ReloadMusicData(ctx context.Context) error
+	ClearCache(ctx context.Context) error
+	UploadLogs(ctx context.Context) error
+	Logout(ctx context.Context) error
+}
+
+// service получает команды с сервера, выполняет их и подтверждает результат.
+type service struct {
+	channel    domain.CommandChannel
+	controller Controller
+	// результаты последних команд по ID: повторно доставленная команда не выполняется второй раз
+	done      map[string]*domain.CommandResult
+	doneOrder []string
+
+	logger zerolog.Logger
+}
+
+func NewService(channel domain.CommandChannel, controller Controller, logger zerolog.Logger) *service {
+	return &service{
+		channel:    channel,
+		controller: controller,
+		done:       make(map[string]*domain.CommandResult),
+		logger:     logger.With().Str("service", "remote").Logger(),
+	}
+}
+
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	failures := 0
+
+	for {
+		startedAt := time.Now()
+		commands, err := s.channel.Receive(ctx)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			failures++
+			delay := utils.Backoff(failures, retryMin, retryMax)
+
+			s.logger.Warn().Err(err).Int("failures", failures).Dur("retryIn", delay).Msg("commands receive fail")
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			continue
+		}
+
+		failures = 0
+
+		// сервер без long polling не должен превращать цикл в частый опрос
+		if wait := minPollInterval - time.Since(startedAt); len(commands) == 0 && wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		for _, cmd := range commands {
+			// после выхода подтвердить будет нечем: токена уже нет
+			if cmd.Type == domain.CommandLogout {
+				s.ack(ctx, &domain.CommandResult{CommandID: cmd.ID, OK: true})
+				s.execute(ctx, cmd)
+
+				break
+			}
+
+			s.ack(ctx, s.execute(ctx, cmd))
+		}
+	}
+}
+
+// ack - при ошибке сервер пришлет команду снова, результат возьмется из done.
+func (s *service) ack(ctx context.Context, result *domain.CommandResult) {
+	if err := s.channel.Ack(ctx, result); err != nil {
+		s.logger.Warn().Err(err).Str("commandId", result.CommandID).Msg("command ack fail")
+	}
+}
+
+func (s *service) execute(ctx context.Context, cmd *domain.Command) *domain.CommandResult {
+	if result, ok := s.done[cmd.ID]; ok {
+		s.logger.Debug().Str("commandId", cmd.ID).Msg("command already done")
+		return result
+	}
+
+	result := &domain.CommandResult{CommandID: cmd.ID, OK: true}
+
+	if err := s.call(ctx, cmd); err != nil {
+		result.OK = false
+		result.Message = err.Error()
+
+		s.logger.Warn().Err(err).Str("commandId", cmd.ID).Str("type", string(cmd.Type)).Msg("command rejected")
+	} else {
+		s.logger.Info().Str("commandId", cmd.ID).Str("type", string(cmd.Type)).Msg("command done")
+	}
+
+	s.remember(result)
+
+	return result
+}
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errInvalidVolume  = errors.New("volume must be between 0 and 1")
+)
+
+// call выполняет команду, паника не роняет плеер.
+func (s *service) call(ctx context.Context, cmd *domain.Command) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			//nolint:goerr113
+			err = fmt.Errorf("command panic: %v", r)
+		}
+	}()
+
+	switch cmd.Type {
+	case domain.CommandSkip:
+		s.controller.Skip()
+
+	case domain.CommandPause:
+		s.controller.Pause()
+
+	case domain.CommandResume:
+		s.controller.Resume()
+
+	case domain.CommandSetVolume:
+		if cmd.Volume == nil || *cmd.Volume < 0 || *cmd.Volume > 1 {
+			return errInvalidVolume
+		}
+
+		s.controller.SetVolume(*cmd.Volume)
+
+	case domain.CommandReloadMusicData:
+		return s.controller.ReloadMusicData(ctx)
+
+	case domain.CommandClearCache:
+		return s.controller.ClearCache(ctx)
+
+	case domain.CommandUploadLogs:
+		return s.controller.UploadLogs(ctx)
+
+	case domain.CommandLogout:
+		return s.controller.Logout(ctx)
+
+	default:
+		return fmt.Errorf("%w: %s", errUnknownCommand, cmd.Type)
+	}
+
+	return nil
+}
+
+func (s *service) remember(result *domain.CommandResult) {
+	if result.CommandID == "" {
+		return
+	}
+
+	s.done[result.CommandID] = result
+	s.doneOrder = append(s.doneOrder, result.CommandID)
+
+	if len(s.doneOrder) > doneMaxLen {
+		delete(s.done, s.doneOrder[0])
+		s.doneOrder = s.doneOrder[1:]
+	}
+}
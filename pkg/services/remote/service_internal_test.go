@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/api"
+	"github.com/qkveri/player_core/pkg/domain/repositories"
+)
+
+type fakeController struct {
+	mu    sync.Mutex
+	calls []string
+
+	server *fakeServer
+	// сколько подтверждений сервер получил к моменту выхода
+	acksBeforeLogout int
+}
+
+func (f *fakeController) record(call string) {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+}
+
+func (f *fakeController) Skip()             { f.record("skip") }
+func (f *fakeController) Pause()            { f.record("pause") }
+func (f *fakeController) Resume()           { f.record("resume") }
+func (f *fakeController) SetVolume(float64) { f.record("setVolume") }
+func (f *fakeController) ClearCache(context.Context) error {
+	f.record("clearCache")
+	return nil
+}
+
+func (f *fakeController) ReloadMusicData(context.Context) error {
+	f.record("reloadMusicData")
+	return errors.New("no internet")
+}
+
+func (f *fakeController) UploadLogs(context.Context) error {
+	panic("log file closed")
+}
+
+func (f *fakeController) Logout(context.Context) error {
+	f.record("logout")
+
+	f.mu.Lock()
+	f.acksBeforeLogout = f.server.ackCount()
+	f.mu.Unlock()
+
+	return nil
+}
+
+type ack struct {
+	ID      string `json:"id"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// fakeServer отдает команды одной пачкой, дальше - пустые ответы.
+type fakeServer struct {
+	mu       sync.Mutex
+	commands []map[string]interface{}
+	acks     []ack
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var data interface{}
+
+	switch {
+	case r.URL.Path == "/return_204":
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	case r.URL.Path == "/player/commands" && r.Method == http.MethodGet:
+		if r.URL.Query().Get("wait") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data = f.commands
+		f.commands = []map[string]interface{}{}
+
+	case r.URL.Path == "/player/commands/ack" && r.Method == http.MethodPost:
+		var a ack
+
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.acks = append(f.acks, a)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func (f *fakeServer) ackCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.acks)
+}
+
+func Test_Run(t *testing.T) {
+	server := &fakeServer{commands: []map[string]interface{}{
+		{"id": "1", "type": "skip"},
+		{"id": "2", "type": "setVolume", "params": map[string]interface{}{"volume": 1.5}},
+		{"id": "3", "type": "setVolume", "params": map[string]interface{}{"volume": 0.5}},
+		{"id": "4", "type": "selfDestruct"},
+		{"id": "5", "type": "reloadMusicData"},
+		{"id": "6", "type": "uploadLogs"},
+		{"id": "7", "type": "clearCache"},
+		{"id": "8", "type": "pause"},
+		{"id": "9", "type": "resume"},
+		// ack потерялся, сервер прислал команду снова
+		{"id": "1", "type": "skip"},
+		{"id": "10", "type": "logout"},
+		// после выхода не выполняется
+		{"id": "11", "type": "skip"},
+	}}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	controller := &fakeController{server: server}
+	svc := NewService(repositories.NewCommandApiRepo(api.NewHTTPClient(srv.URL)), controller, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- svc.Run(ctx)
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); server.ackCount() < 11 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got Run error %v, want context.Canceled", err)
+	}
+
+	wantCalls := []string{"skip", "setVolume", "reloadMusicData", "clearCache", "pause", "resume", "logout"}
+
+	if !reflect.DeepEqual(controller.calls, wantCalls) {
+		t.Errorf("got calls %q, want %q", controller.calls, wantCalls)
+	}
+
+	// после выхода токена нет, поэтому выход подтверждается заранее
+	if controller.acksBeforeLogout != 11 {
+		t.Errorf("got %d acks before logout, want 11", controller.acksBeforeLogout)
+	}
+
+	wantOK := []bool{true, false, true, false, false, false, true, true, true, true, true}
+
+	if len(server.acks) != len(wantOK) {
+		t.Fatalf("got %d acks, want %d", len(server.acks), len(wantOK))
+	}
+
+	for i, a := range server.acks {
+		if a.OK != wantOK[i] {
+			t.Errorf("ack %s: got ok %v (%s), want %v", a.ID, a.OK, a.Message, wantOK[i])
+		}
+
+		if !a.OK && a.Message == "" {
+			t.Errorf("ack %s: rejected without message", a.ID)
+		}
+	}
+}
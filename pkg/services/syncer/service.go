@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+type service struct {
+	state          *state.State
+	logger         zerolog.Logger
+	playerInfoRepo domain.PlayerInfoRepository
+	musicDataRepo  domain.MusicDataRepository
+	interval       time.Duration
+}
+
+func NewService(
+	state *state.State,
+	logger zerolog.Logger,
+	playerInfoRepo domain.PlayerInfoRepository,
+	musicDataRepo domain.MusicDataRepository,
+	interval time.Duration,
+) *service {
+	return &service{
+		state:          state,
+		logger:         logger.With().Str("service", "syncer").Logger(),
+		playerInfoRepo: playerInfoRepo,
+		musicDataRepo:  musicDataRepo,
+		interval:       interval,
+	}
+}
+
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Dur("interval", s.interval).Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-t.C:
+			if err := s.sync(ctx, false); err != nil {
+				s.logger.Warn().Err(err).Msg("musicData sync failed")
+			}
+		}
+	}
+}
+
+// Reload загружает настройки сразу, плейлист перестраивается, даже если они не изменились.
+func (s *service) Reload(ctx context.Context) error {
+	return s.sync(ctx, true)
+}
+
+func (s *service) sync(ctx context.Context, force bool) error {
+	s.state.MusicData.RLock()
+	current := s.state.MusicData.Get()
+	s.state.MusicData.RUnlock()
+
+	// первичная загрузка - в App.LoadData
+	if current == nil {
+		s.logger.Debug().Msg("sync skipped (musicData not loaded)")
+		return nil
+	}
+
+	s.logger.Debug().Msg("sync starts...")
+
+	playerInfo, err := s.playerInfoRepo.Get(ctx)
+
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("playerInfo sync failed")
+	} else {
+		s.state.PlayerInfo.Lock()
+		s.state.PlayerInfo.Set(playerInfo)
+		s.state.PlayerInfo.Unlock()
+	}
+
+	musicData, err := s.musicDataRepo.Get(ctx)
+
+	if err != nil {
+		return fmt.Errorf("cannot get musicData: %w", err)
+	}
+
+	if musicData.Hash == current.Hash && !force {
+		s.logger.Debug().Str("hash", musicData.Hash).Msg("musicData not changed")
+		return nil
+	}
+
+	s.logger.Info().Str("oldHash", current.Hash).Str("newHash", musicData.Hash).Msg("musicData changed")
+
+	// плейлистер подхватит новый указатель, текущий трек плеера доиграет
+	s.state.MusicData.Lock()
+	s.state.MusicData.Set(musicData)
+	s.state.MusicData.Unlock()
+
+	return nil
+}
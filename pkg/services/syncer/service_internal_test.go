@@ -0,0 +1,131 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+	"github.com/qkveri/player_core/pkg/state"
+)
+
+var errTest = errors.New("test error")
+
+type fakePlayerInfoRepo struct {
+	playerInfo *domain.PlayerInfo
+	err        error
+}
+
+func (f *fakePlayerInfoRepo) Get(context.Context) (*domain.PlayerInfo, error) {
+	return f.playerInfo, f.err
+}
+
+type fakeMusicDataRepo struct {
+	musicData *domain.MusicData
+	err       error
+	calls     int
+}
+
+func (f *fakeMusicDataRepo) Get(context.Context) (*domain.MusicData, error) {
+	f.calls++
+	return f.musicData, f.err
+}
+
+func Test_sync(t *testing.T) {
+	current := &domain.MusicData{Hash: "h1"}
+	same := &domain.MusicData{Hash: "h1"}
+	changed := &domain.MusicData{Hash: "h2"}
+	oldInfo, newInfo := &domain.PlayerInfo{}, &domain.PlayerInfo{}
+
+	tests := []struct {
+		name           string
+		current        *domain.MusicData
+		playerInfoRepo *fakePlayerInfoRepo
+		musicDataRepo  *fakeMusicDataRepo
+		force          bool
+		wantErr        bool
+		wantMusicData  *domain.MusicData
+		wantPlayerInfo *domain.PlayerInfo
+	}{
+		{
+			name:           "not loaded",
+			playerInfoRepo: &fakePlayerInfoRepo{playerInfo: newInfo},
+			musicDataRepo:  &fakeMusicDataRepo{musicData: changed},
+			wantPlayerInfo: oldInfo,
+		},
+		{
+			name:           "hash not changed",
+			current:        current,
+			playerInfoRepo: &fakePlayerInfoRepo{playerInfo: newInfo},
+			musicDataRepo:  &fakeMusicDataRepo{musicData: same},
+			wantMusicData:  current,
+			wantPlayerInfo: newInfo,
+		},
+		{
+			name:           "hash changed",
+			current:        current,
+			playerInfoRepo: &fakePlayerInfoRepo{playerInfo: newInfo},
+			musicDataRepo:  &fakeMusicDataRepo{musicData: changed},
+			wantMusicData:  changed,
+			wantPlayerInfo: newInfo,
+		},
+		{
+			name:           "hash not changed, forced",
+			current:        current,
+			playerInfoRepo: &fakePlayerInfoRepo{playerInfo: newInfo},
+			musicDataRepo:  &fakeMusicDataRepo{musicData: same},
+			force:          true,
+			wantMusicData:  same,
+			wantPlayerInfo: newInfo,
+		},
+		{
+			name:           "playerInfo failed",
+			current:        current,
+			playerInfoRepo: &fakePlayerInfoRepo{err: errTest},
+			musicDataRepo:  &fakeMusicDataRepo{musicData: changed},
+			wantMusicData:  changed,
+			wantPlayerInfo: oldInfo,
+		},
+		{
+			name:           "musicData failed",
+			current:        current,
+			playerInfoRepo: &fakePlayerInfoRepo{playerInfo: newInfo},
+			musicDataRepo:  &fakeMusicDataRepo{err: errTest},
+			wantErr:        true,
+			wantMusicData:  current,
+			wantPlayerInfo: newInfo,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			st := state.NewState()
+			st.PlayerInfo.Set(oldInfo)
+			st.MusicData.Set(tt.current)
+
+			s := NewService(st, zerolog.Nop(), tt.playerInfoRepo, tt.musicDataRepo, time.Minute)
+
+			if err := s.sync(context.Background(), tt.force); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.current == nil && tt.musicDataRepo.calls != 0 {
+				t.Errorf("musicData requested before initial load")
+			}
+
+			// сравниваются указатели: при том же хеше state не меняется
+			if got := st.MusicData.Get(); got != tt.wantMusicData {
+				t.Errorf("got musicData %p, want %p", got, tt.wantMusicData)
+			}
+
+			if got := st.PlayerInfo.Get(); got != tt.wantPlayerInfo {
+				t.Errorf("got playerInfo %p, want %p", got, tt.wantPlayerInfo)
+			}
+		})
+	}
+}
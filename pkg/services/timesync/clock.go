@@ -0,0 +1,37 @@
+package timesync
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// offsetClock - часы устройства, поправленные на расхождение с сервером.
+// Сдвигается только Now, интервалы и таймеры идут по часам устройства.
+type offsetClock struct {
+	clockwork.Clock
+
+	// time.Duration
+	offset int64
+}
+
+func newOffsetClock(base clockwork.Clock) *offsetClock {
+	return &offsetClock{Clock: base}
+}
+
+func (c *offsetClock) Now() time.Time {
+	return c.Clock.Now().Add(c.Offset())
+}
+
+func (c *offsetClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *offsetClock) Offset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.offset))
+}
+
+func (c *offsetClock) setOffset(offset time.Duration) {
+	atomic.StoreInt64(&c.offset, int64(offset))
+}
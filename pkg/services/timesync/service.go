@@ -0,0 +1,133 @@
+package timesync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+const (
+	// серверное время приходит с точностью до секунды, в среднем оно отстает на полсекунды
+	serverTimePrecision = time.Second
+	// меньшие изменения расхождения - шум, часы не трогаем
+	minAdjust = time.Second
+)
+
+type DriftError struct {
+	Drift time.Duration
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("device clock drift: %s", e.Drift)
+}
+
+type service struct {
+	clock *offsetClock
+	// расхождение уже показано пользователю
+	driftReported bool
+
+	base           clockwork.Clock
+	logger         zerolog.Logger
+	errCb          func(error)
+	playerInfoRepo domain.PlayerInfoRepository
+	interval       time.Duration
+	driftThreshold time.Duration
+}
+
+// NewService - playerInfoRepo должен ходить в API: сохраненный на устройстве PlayerInfo
+// содержит устаревшее серверное время.
+func NewService(
+	base clockwork.Clock,
+	logger zerolog.Logger,
+	errCb func(error),
+	playerInfoRepo domain.PlayerInfoRepository,
+	interval time.Duration,
+	driftThreshold time.Duration,
+) *service {
+	return &service{
+		clock:          newOffsetClock(base),
+		base:           base,
+		logger:         logger.With().Str("service", "timesync").Logger(),
+		errCb:          errCb,
+		playerInfoRepo: playerInfoRepo,
+		interval:       interval,
+		driftThreshold: driftThreshold,
+	}
+}
+
+// Clock - часы с серверным временем. До первой синхронизации идут как часы устройства.
+func (s *service) Clock() clockwork.Clock {
+	return s.clock
+}
+
+// Offset - насколько серверное время впереди часов устройства.
+func (s *service) Offset() time.Duration {
+	return s.clock.Offset()
+}
+
+func (s *service) Run(ctx context.Context) error {
+	s.logger.Debug().Dur("interval", s.interval).Msg("starts up")
+	defer s.logger.Debug().Msg("stopped")
+
+	s.sync(ctx)
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-t.C:
+			s.sync(ctx)
+		}
+	}
+}
+
+// sync оценивает серверное время на момент ответа: ServerTime плюс половина времени запроса.
+func (s *service) sync(ctx context.Context) {
+	sentAt := s.base.Now()
+
+	playerInfo, err := s.playerInfoRepo.Get(ctx)
+
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("time sync failed")
+		return
+	}
+
+	receivedAt := s.base.Now()
+	rtt := receivedAt.Sub(sentAt)
+
+	if playerInfo.ServerTime.Unix() <= 0 {
+		s.logger.Warn().Msg("time sync skipped (no server time)")
+		return
+	}
+
+	serverNow := playerInfo.ServerTime.Add(serverTimePrecision/2 + rtt/2)
+	offset := serverNow.Sub(receivedAt)
+
+	s.logger.Debug().Dur("offset", offset).Dur("rtt", rtt).Msg("time synced")
+
+	if d := offset - s.clock.Offset(); d > minAdjust || d < -minAdjust {
+		s.logger.Info().Dur("offset", offset).Dur("previous", s.clock.Offset()).Msg("clock offset changed")
+		s.clock.setOffset(offset)
+	}
+
+	if offset < s.driftThreshold && offset > -s.driftThreshold {
+		s.driftReported = false
+		return
+	}
+
+	s.logger.Warn().Dur("offset", offset).Dur("threshold", s.driftThreshold).Msg("device clock drift")
+
+	if !s.driftReported {
+		s.driftReported = true
+		s.errCb(&DriftError{Drift: offset})
+	}
+}
@@ -0,0 +1,57 @@
+package timesync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/rs/zerolog"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// fakePlayerInfoRepo отвечает серверным временем за rtt.
+type fakePlayerInfoRepo struct {
+	clock      clockwork.FakeClock
+	serverTime time.Time
+	rtt        time.Duration
+}
+
+func (f *fakePlayerInfoRepo) Get(context.Context) (*domain.PlayerInfo, error) {
+	f.clock.Advance(f.rtt)
+
+	return &domain.PlayerInfo{ServerTime: f.serverTime}, nil
+}
+
+func Test_sync(t *testing.T) {
+	deviceNow := time.Date(2021, time.March, 1, 3, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(deviceNow)
+
+	// сервер на 6 часов впереди, время ответа - на середине запроса
+	repo := &fakePlayerInfoRepo{clock: clock, serverTime: deviceNow.Add(6*time.Hour + time.Second), rtt: 2 * time.Second}
+
+	var reported []error
+
+	svc := NewService(clock, zerolog.Nop(), func(err error) { reported = append(reported, err) }, repo,
+		time.Hour, time.Minute)
+
+	svc.sync(context.Background())
+
+	want := deviceNow.Add(6*time.Hour + 2*time.Second + serverTimePrecision/2)
+
+	if got := svc.Clock().Now(); !got.Equal(want) {
+		t.Errorf("got now %s, want %s", got, want)
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reported))
+	}
+
+	// повторное расхождение не показывается
+	svc.sync(context.Background())
+
+	if len(reported) != 1 {
+		t.Errorf("got %d reports, want 1", len(reported))
+	}
+}
@@ -0,0 +1,25 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type cache struct {
+	sync.RWMutex
+
+	coverage domain.CacheCoverage
+}
+
+func newCache() cache {
+	return cache{}
+}
+
+func (c *cache) SetCoverage(coverage domain.CacheCoverage) {
+	c.coverage = coverage
+}
+
+func (c *cache) Coverage() domain.CacheCoverage {
+	return c.coverage
+}
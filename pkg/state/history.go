@@ -0,0 +1,42 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+// historyMaxLen - сколько последних треков помнить
+const historyMaxLen = 1000
+
+type history struct {
+	sync.RWMutex
+
+	entries []*domain.HistoryEntry
+}
+
+func newHistory() history {
+	return history{}
+}
+
+// Set заменяет историю, например сохраненной на устройстве.
+func (h *history) Set(entries []*domain.HistoryEntry) {
+	if len(entries) > historyMaxLen {
+		entries = entries[len(entries)-historyMaxLen:]
+	}
+
+	h.entries = append([]*domain.HistoryEntry(nil), entries...)
+}
+
+func (h *history) Add(entry *domain.HistoryEntry) {
+	h.entries = append(h.entries, entry)
+
+	if len(h.entries) > historyMaxLen {
+		h.entries = append([]*domain.HistoryEntry(nil), h.entries[len(h.entries)-historyMaxLen:]...)
+	}
+}
+
+// Entries - от старых к новым.
+func (h *history) Entries() []*domain.HistoryEntry {
+	return h.entries
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qkveri/player_core/pkg/domain"
+)
+
+type player struct {
+	sync.RWMutex
+
+	status   domain.PlayerStatus
+	current  *domain.PlaylistTrack
+	position time.Duration
+}
+
+func newPlayer() player {
+	return player{
+		status: domain.PlayerStatusStopped,
+	}
+}
+
+func (p *player) Set(status domain.PlayerStatus, current *domain.PlaylistTrack, position time.Duration) {
+	p.status = status
+	p.current = current
+	p.position = position
+}
+
+func (p *player) Status() domain.PlayerStatus {
+	return p.status
+}
+
+func (p *player) Current() *domain.PlaylistTrack {
+	return p.current
+}
+
+func (p *player) Position() time.Duration {
+	return p.position
+}
+
+// Remaining - сколько осталось играть текущему треку.
+func (p *player) Remaining() time.Duration {
+	if p.current == nil || p.current.Track == nil || p.position >= p.current.Track.Duration {
+		return 0
+	}
+
+	return p.current.Track.Duration - p.position
+}
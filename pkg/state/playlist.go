@@ -31,6 +31,36 @@ func (p *playlist) Replace(item *domain.PlaylistTrack, index int) {
 	p.items[index] = item
 }
 
+// Shift удаляет и возвращает первый элемент плейлиста.
+func (p *playlist) Shift() *domain.PlaylistTrack {
+	if len(p.items) == 0 {
+		return nil
+	}
+
+	item := p.items[0]
+	p.items = p.items[1:]
+
+	return item
+}
+
+func (p *playlist) Insert(item *domain.PlaylistTrack, index int) {
+	p.items = append(p.items, nil)
+	copy(p.items[index+1:], p.items[index:])
+	p.items[index] = item
+}
+
+// Truncate оставляет в плейлисте первые n элементов и возвращает удаленные.
+func (p *playlist) Truncate(n int) []*domain.PlaylistTrack {
+	if len(p.items) <= n {
+		return nil
+	}
+
+	removed := append([]*domain.PlaylistTrack(nil), p.items[n:]...)
+	p.items = p.items[:n]
+
+	return removed
+}
+
 func (p *playlist) SetDownloadProgress(item *domain.PlaylistTrack, downloadProgress progress.Progress) {
 	for i, el := range p.items {
 		if el == item {
@@ -1,9 +1,16 @@
 package state
 
+import "time"
+
 type State struct {
 	PlayerInfo playerInfo
 	MusicData  musicData
 	Playlist   playlist
+	Player     player
+	Cache      cache
+	Tracks     tracks
+	History    history
+	Venue      venue
 }
 
 func NewState() *State {
@@ -11,5 +18,35 @@ func NewState() *State {
 		PlayerInfo: newPlayerInfo(),
 		MusicData:  newMusicData(),
 		Playlist:   newPlaylist(),
+		Player:     newPlayer(),
+		Cache:      newCache(),
+		Tracks:     newTracks(),
+		History:    newHistory(),
+		Venue:      newVenue(),
 	}
 }
+
+// Reset забывает данные заведения после выхода. Блокировки берет сам, по одной.
+// Плеер, доступность и кеш треков не зависят от заведения и остаются.
+func (s *State) Reset() {
+	s.PlayerInfo.Lock()
+	s.PlayerInfo.Set(nil)
+	s.PlayerInfo.Unlock()
+
+	s.MusicData.Lock()
+	s.MusicData.Set(nil)
+	s.MusicData.Unlock()
+
+	s.Playlist.Lock()
+	s.Playlist.Truncate(0)
+	s.Playlist.Unlock()
+
+	s.History.Lock()
+	s.History.Set(nil)
+	s.History.Unlock()
+
+	s.Venue.Lock()
+	s.Venue.Set(false, time.Time{})
+	s.Venue.SetVolume(1)
+	s.Venue.Unlock()
+}
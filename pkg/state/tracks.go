@@ -0,0 +1,42 @@
+package state
+
+import (
+	"sync"
+)
+
+// tracks - треки, которые не удается скачать.
+type tracks struct {
+	sync.RWMutex
+
+	unavailable map[int]struct{}
+}
+
+func newTracks() tracks {
+	return tracks{
+		unavailable: make(map[int]struct{}),
+	}
+}
+
+func (t *tracks) SetUnavailable(trackID int) {
+	t.unavailable[trackID] = struct{}{}
+}
+
+func (t *tracks) SetAvailable(trackID int) {
+	delete(t.unavailable, trackID)
+}
+
+func (t *tracks) IsUnavailable(trackID int) bool {
+	_, ok := t.unavailable[trackID]
+
+	return ok
+}
+
+func (t *tracks) Unavailable() map[int]struct{} {
+	unavailable := make(map[int]struct{}, len(t.unavailable))
+
+	for trackID := range t.unavailable {
+		unavailable[trackID] = struct{}{}
+	}
+
+	return unavailable
+}
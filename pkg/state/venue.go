@@ -0,0 +1,42 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// venue - открыто ли заведение.
+type venue struct {
+	sync.RWMutex
+
+	closed bool
+	// когда откроется, нулевое - неизвестно
+	opensAt time.Time
+	// громкость по расписанию интервалов, 0..1
+	volume float64
+}
+
+func newVenue() venue {
+	return venue{volume: 1}
+}
+
+func (v *venue) Set(closed bool, opensAt time.Time) {
+	v.closed = closed
+	v.opensAt = opensAt
+}
+
+func (v *venue) Closed() bool {
+	return v.closed
+}
+
+func (v *venue) OpensAt() time.Time {
+	return v.opensAt
+}
+
+func (v *venue) SetVolume(volume float64) {
+	v.volume = volume
+}
+
+func (v *venue) Volume() float64 {
+	return v.volume
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "time"
+
+// Backoff - задержка перед попыткой attempt (с 1): min, затем удваивается до max.
+// Если min больше max, всегда min.
+func Backoff(attempt int, min, max time.Duration) time.Duration {
+	d := min
+
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max && min < max {
+		d = max
+	}
+
+	return d
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Backoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		min     time.Duration
+		max     time.Duration
+		want    time.Duration
+	}{
+		{name: "first attempt", attempt: 1, min: time.Second, max: time.Minute, want: time.Second},
+		{name: "no attempts yet", attempt: 0, min: time.Second, max: time.Minute, want: time.Second},
+		{name: "doubled", attempt: 3, min: time.Second, max: time.Minute, want: 4 * time.Second},
+		{name: "capped", attempt: 10, min: time.Second, max: time.Minute, want: time.Minute},
+		{name: "min above max", attempt: 3, min: time.Hour, max: time.Minute, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Backoff(tt.attempt, tt.min, tt.max); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
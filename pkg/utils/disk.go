@@ -0,0 +1,20 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package utils
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeDiskSpace - сколько байт доступно приложению на диске с dir.
+func FreeDiskSpace(dir string) (int64, error) {
+	var st syscall.Statfs_t
+
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, fmt.Errorf("syscall.Statfs: %w", err)
+	}
+
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package utils
+
+import "errors"
+
+// FreeDiskSpace - на этой платформе не поддерживается.
+func FreeDiskSpace(string) (int64, error) {
+	//nolint:goerr113
+	return 0, errors.New("free disk space is not supported")
+}